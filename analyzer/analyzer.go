@@ -21,6 +21,11 @@ var analyzerIdGenerator mdw.IdGenerator = mdw.NewIdGenerator()
 
 var logger logging.Logger = base.NewLogger()
 
+// 响应解析函数。
+// 每个解析函数得到的响应体都是从头开始的缓冲内容，可以完整读取，不需要关闭。
+// 文本内容已被转换为UTF-8，原来的字符集记录在 base.CharsetHeader 头中。
+// 调度器的上下文可以通过 httpResp.Request.Context() 获得，
+// 耗时较长的解析函数应该在它被取消时尽早返回。
 type ParseResponse func(httpResp *http.Response, respDepth uint32) ([]base.Data, []error)
 
 func genAnalyzerId() uint32 {
@@ -59,25 +64,41 @@ func (analyzer *myAnalyzer) Analyze(respParsers []ParseResponse, resp base.Respo
 
 	logger.Infof("Parse the response (reqUrl=%s)...\n", reqUrl)
 
+	// 由自定义下载器得到的响应可能还没有被缓冲。
+	if err := resp.Buffer(base.DefaultMaxBodySize); err != nil {
+		errMsg := fmt.Sprintf("Read response body error (reqUrl=%s): %s\n", reqUrl, err)
+		return nil, []error{errors.New(errMsg)}
+	}
+	if resp.Truncated() {
+		logger.Warnf("The response body is truncated to %d bytes (reqUrl=%s).\n", len(resp.Body()), reqUrl)
+	}
+
 	respDepth := resp.Depth()
 
 	dataList = make([]base.Data, 0)
 	errorList = make([]error, 0)
 
+	ctx := httpResp.Request.Context()
 	for i, respParser := range respParsers {
+		if err := ctx.Err(); err != nil {
+			errMsg := fmt.Sprintf("The analysis is canceled: %s (reqUrl=%s)\n", err, reqUrl)
+			errorList = append(errorList, errors.New(errMsg))
+			break
+		}
 		if respParser == nil {
 			err := errors.New(fmt.Sprintf("The document parser [%d] is invalid!\n", i))
 			errorList = append(errorList, err)
 			continue
 		}
+		httpResp.Body = resp.TextReader()
 		pDataList, pErrorList := respParser(httpResp, respDepth)
 		if pDataList != nil {
 			for _, pData := range pDataList {
-				dataList = appendDataList(dataList, pData, respDepth)
+				dataList = appendDataList(dataList, pData, respDepth, reqUrl)
 			}
 		}
 		if pErrorList != nil {
-			for _, err := range errorList {
+			for _, err := range pErrorList {
 				errorList = appendErrorList(errorList, err)
 			}
 		}
@@ -85,7 +106,7 @@ func (analyzer *myAnalyzer) Analyze(respParsers []ParseResponse, resp base.Respo
 	return
 }
 
-func appendDataList(dataList []base.Data, data base.Data, respDepth uint32) []base.Data {
+func appendDataList(dataList []base.Data, data base.Data, respDepth uint32, parentUrl *url.URL) []base.Data {
 	if data == nil {
 		return dataList
 	}
@@ -96,7 +117,13 @@ func appendDataList(dataList []base.Data, data base.Data, respDepth uint32) []ba
 
 	newDepth := respDepth + 1
 	if req.Depth() != newDepth {
+		priority, tag := req.Priority(), req.Tag()
 		req = base.NewRequest(req.HttpReq(), newDepth)
+		req.SetPriority(priority)
+		req.SetTag(tag)
+	}
+	if req.ParentUrl() == "" {
+		req.SetParentUrl(parentUrl.String())
 	}
 	return append(dataList, req)
 }
@@ -0,0 +1,55 @@
+package analyzer
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"webcrawler/base"
+)
+
+// 创建用于测试的响应。
+func newTestResponse(rawurl string, contentType string, body string) *http.Response {
+	httpReq, _ := http.NewRequest("GET", rawurl, nil)
+	header := make(http.Header)
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     header,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    httpReq,
+	}
+}
+
+func TestAnalyzeCollectsParserErrors(t *testing.T) {
+	httpResp := newTestResponse("http://example.com/", "text/html", "<html></html>")
+	resp := base.NewResponse(httpResp, 0)
+	parsers := []ParseResponse{
+		func(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
+			item := base.Item{"a": 1}
+			return []base.Data{&item}, []error{errors.New("first")}
+		},
+		func(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
+			body, _ := ioutil.ReadAll(httpResp.Body)
+			if string(body) != "<html></html>" {
+				return nil, []error{errors.New("unexpected body")}
+			}
+			httpReq, _ := http.NewRequest("GET", "http://example.com/next", nil)
+			return []base.Data{base.NewRequest(httpReq, 0)}, []error{errors.New("second"), nil}
+		},
+	}
+	dataList, errs := NewAnalyzer().Analyze(parsers, *resp)
+	if len(dataList) != 2 {
+		t.Fatalf("len(dataList) = %d, want 2", len(dataList))
+	}
+	req, ok := dataList[1].(*base.Request)
+	if !ok || req.Depth() != 1 || req.ParentUrl() != "http://example.com/" {
+		t.Fatalf("Unexpected request: %#v", dataList[1])
+	}
+	if len(errs) != 2 || errs[0].Error() != "first" || errs[1].Error() != "second" {
+		t.Fatalf("errs = %v, want [first second]", errs)
+	}
+}
@@ -0,0 +1,409 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 23:48:05
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 23:48:05
+ */
+
+package analyzer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+	"gopkg.in/yaml.v2"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"webcrawler/base"
+)
+
+// 记录生成item的规则名称的键。
+const ItemRuleKey = "_rule"
+
+// 字段值的取值方式。
+const (
+	FIELD_MODE_TEXT = "text" // 元素的文本（默认）。
+	FIELD_MODE_HTML = "html" // 元素的内部HTML。
+	FIELD_MODE_ATTR = "attr" // 元素的属性，设置了attr时的默认方式。
+)
+
+// 字段值的类型。
+const (
+	FIELD_TYPE_STRING = "string" // 默认。
+	FIELD_TYPE_INT    = "int"
+	FIELD_TYPE_FLOAT  = "float"
+	FIELD_TYPE_BOOL   = "bool"
+)
+
+// 声明式的item提取规则，可以从YAML或JSON文件加载。
+type ItemRules struct {
+	Items []ItemRule `json:"items" yaml:"items"`
+}
+
+// 一种item的提取规则，每个匹配选择器的元素生成一个item。
+// css和xpath都为空时整个文档生成一个item。
+type ItemRule struct {
+	Name   string      `json:"name" yaml:"name"` // 不为空时记录在item的ItemRuleKey键中。
+	Css    string      `json:"css" yaml:"css"`
+	Xpath  string      `json:"xpath" yaml:"xpath"`
+	Fields []FieldRule `json:"fields" yaml:"fields"`
+}
+
+// 字段的提取规则，选择器相对于item（或上层字段）的元素，都为空时使用该元素本身。
+type FieldRule struct {
+	Name     string      `json:"name" yaml:"name"`
+	Css      string      `json:"css" yaml:"css"`
+	Xpath    string      `json:"xpath" yaml:"xpath"`
+	Mode     string      `json:"mode" yaml:"mode"`
+	Attr     string      `json:"attr" yaml:"attr"`
+	Regex    string      `json:"regex" yaml:"regex"` // 有分组时取第一个分组，不匹配时视为没有值。
+	Type     string      `json:"type" yaml:"type"`
+	List     bool        `json:"list" yaml:"list"`         // 是否取所有匹配的元素，否则只取第一个。
+	Required bool        `json:"required" yaml:"required"` // 没有值时丢弃整个item。
+	Default  interface{} `json:"default" yaml:"default"`   // 没有值时使用的值。
+	Fields   []FieldRule `json:"fields" yaml:"fields"`     // 不为空时每个元素生成一个嵌套的item。
+	Fallback *FieldRule  `json:"fallback" yaml:"fallback"` // 没有值或值为空字符串时改用的规则，不需要设置名称。
+}
+
+// 从YAML（.yaml、.yml）或JSON（.json）文件加载item提取规则。
+func LoadItemRules(path string) (ItemRules, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ItemRules{}, err
+	}
+	rules, err := ParseItemRules(data, filepath.Ext(path))
+	if err != nil {
+		return rules, errors.New(fmt.Sprintf("Load item rules error (path=%s): %s", path, err))
+	}
+	return rules, nil
+}
+
+// 解析item提取规则，format为 yaml、yml 或 json（可以带有前缀的点，如文件的扩展名）。
+func ParseItemRules(data []byte, format string) (ItemRules, error) {
+	var rules ItemRules
+	var err error
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "yaml", "yml":
+		err = yaml.UnmarshalStrict(data, &rules)
+	case "json":
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+		err = decoder.Decode(&rules)
+	default:
+		return rules, errors.New(fmt.Sprintf("Unsupported item rules format %q!\n", format))
+	}
+	if err != nil {
+		return rules, errors.New(fmt.Sprintf("Parse item rules error: %s\n", err))
+	}
+	return rules, nil
+}
+
+// item提取器。
+type ItemExtractor interface {
+	// 提取HTML响应中的item，状态码不是2xx时返回nil。
+	Extract(httpResp *http.Response) ([]base.Item, []error)
+	// 用于 ParseResponse 的解析函数。
+	// 缺少必需的字段或类型转换出错的item被丢弃，并返回相应的错误。
+	Parse(httpResp *http.Response, respDepth uint32) ([]base.Data, []error)
+}
+
+// 编译后的选择器，为nil时选择元素本身。
+type nodeSelector func(node *html.Node) []*html.Node
+
+type itemMatcher struct {
+	name     string
+	selector nodeSelector
+	fields   []*fieldMatcher
+}
+
+type fieldMatcher struct {
+	rule     FieldRule
+	selector nodeSelector
+	regex    *regexp.Regexp
+	fields   []*fieldMatcher
+	fallback *fieldMatcher
+}
+
+type myItemExtractor struct {
+	items []*itemMatcher
+}
+
+func NewItemExtractor(rules ItemRules) (ItemExtractor, error) {
+	if len(rules.Items) == 0 {
+		return nil, errors.New("The item rule list is empty!\n")
+	}
+	items := make([]*itemMatcher, 0, len(rules.Items))
+	for i, rule := range rules.Items {
+		path := rule.Name
+		if path == "" {
+			path = fmt.Sprintf("items[%d]", i)
+		}
+		selector, err := compileNodeSelector(path, rule.Css, rule.Xpath)
+		if err != nil {
+			return nil, err
+		}
+		if len(rule.Fields) == 0 {
+			return nil, errors.New(fmt.Sprintf("The field list of %s is empty!\n", path))
+		}
+		fields, err := compileFieldMatchers(path, rule.Fields)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, &itemMatcher{name: rule.Name, selector: selector, fields: fields})
+	}
+	return &myItemExtractor{items: items}, nil
+}
+
+func compileNodeSelector(path string, css string, xpathExpr string) (nodeSelector, error) {
+	switch {
+	case css != "" && xpathExpr != "":
+		return nil, errors.New(fmt.Sprintf("Both css and xpath are set for %s!\n", path))
+	case css != "":
+		sel, err := cascadia.Compile(css)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid css selector %q for %s: %s\n", css, path, err))
+		}
+		return sel.MatchAll, nil
+	case xpathExpr != "":
+		expr, err := xpath.Compile(xpathExpr)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid xpath %q for %s: %s\n", xpathExpr, path, err))
+		}
+		return func(node *html.Node) []*html.Node {
+			return htmlquery.QuerySelectorAll(node, expr)
+		}, nil
+	}
+	return nil, nil
+}
+
+func compileFieldMatchers(parent string, rules []FieldRule) ([]*fieldMatcher, error) {
+	fields := make([]*fieldMatcher, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Name == "" {
+			return nil, errors.New(fmt.Sprintf("A field name of %s is empty!\n", parent))
+		}
+		path := parent + "." + rule.Name
+		selector, err := compileNodeSelector(path, rule.Css, rule.Xpath)
+		if err != nil {
+			return nil, err
+		}
+		if rule.Mode == "" {
+			rule.Mode = FIELD_MODE_TEXT
+			if rule.Attr != "" {
+				rule.Mode = FIELD_MODE_ATTR
+			}
+		}
+		switch rule.Mode {
+		case FIELD_MODE_TEXT, FIELD_MODE_HTML:
+		case FIELD_MODE_ATTR:
+			if rule.Attr == "" {
+				return nil, errors.New(fmt.Sprintf("The attr of %s is empty!\n", path))
+			}
+		default:
+			return nil, errors.New(fmt.Sprintf("Unsupported mode %q for %s!\n", rule.Mode, path))
+		}
+		switch rule.Type {
+		case "", FIELD_TYPE_STRING, FIELD_TYPE_INT, FIELD_TYPE_FLOAT, FIELD_TYPE_BOOL:
+		default:
+			return nil, errors.New(fmt.Sprintf("Unsupported type %q for %s!\n", rule.Type, path))
+		}
+		field := &fieldMatcher{rule: rule, selector: selector}
+		if rule.Regex != "" {
+			field.regex, err = regexp.Compile(rule.Regex)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid regex %q for %s: %s\n", rule.Regex, path, err))
+			}
+		}
+		if len(rule.Fields) > 0 {
+			field.fields, err = compileFieldMatchers(path, rule.Fields)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if rule.Fallback != nil {
+			fallback := *rule.Fallback
+			fallback.Name = rule.Name
+			fallbacks, err := compileFieldMatchers(parent, []FieldRule{fallback})
+			if err != nil {
+				return nil, err
+			}
+			field.fallback = fallbacks[0]
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func (ie *myItemExtractor) Extract(httpResp *http.Response) ([]base.Item, []error) {
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+		return nil, nil
+	}
+	doc, err := html.Parse(httpResp.Body)
+	if err != nil {
+		return nil, []error{err}
+	}
+	reqUrl := httpResp.Request.URL
+	items := make([]base.Item, 0)
+	errs := make([]error, 0)
+	for _, matcher := range ie.items {
+		nodes := []*html.Node{doc}
+		if matcher.selector != nil {
+			nodes = matcher.selector(doc)
+		}
+		for _, node := range nodes {
+			imap, err := extractFields(matcher.fields, node)
+			if err != nil {
+				errs = append(errs, errors.New(fmt.Sprintf("%s (reqUrl=%s)\n", strings.TrimSuffix(err.Error(), "\n"), reqUrl)))
+				continue
+			}
+			if matcher.name != "" {
+				imap[ItemRuleKey] = matcher.name
+			}
+			items = append(items, base.Item(imap))
+		}
+	}
+	return items, errs
+}
+
+func (ie *myItemExtractor) Parse(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
+	items, errs := ie.Extract(httpResp)
+	dataList := make([]base.Data, 0, len(items))
+	for i := range items {
+		dataList = append(dataList, &items[i])
+	}
+	return dataList, errs
+}
+
+// 提取元素中的各个字段，缺少必需的字段时返回错误。
+func extractFields(fields []*fieldMatcher, node *html.Node) (map[string]interface{}, error) {
+	imap := make(map[string]interface{})
+	for _, field := range fields {
+		value, err := field.extract(node)
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			value = field.rule.Default
+		}
+		if value == nil {
+			if field.rule.Required {
+				return nil, errors.New(fmt.Sprintf("The required field %s is missing!\n", field.rule.Name))
+			}
+			continue
+		}
+		imap[field.rule.Name] = value
+	}
+	return imap, nil
+}
+
+// 提取字段的值，没有值时返回nil。
+func (field *fieldMatcher) extract(node *html.Node) (interface{}, error) {
+	value, err := field.extractValue(node)
+	if err != nil {
+		return nil, err
+	}
+	if (value == nil || value == "") && field.fallback != nil {
+		fallbackValue, err := field.fallback.extract(node)
+		if err != nil || fallbackValue != nil {
+			return fallbackValue, err
+		}
+	}
+	return value, nil
+}
+
+func (field *fieldMatcher) extractValue(node *html.Node) (interface{}, error) {
+	nodes := []*html.Node{node}
+	if field.selector != nil {
+		nodes = field.selector(node)
+	}
+	values := make([]interface{}, 0, len(nodes))
+	for _, n := range nodes {
+		value, err := field.value(n)
+		if err != nil {
+			if len(field.fields) > 0 {
+				return nil, err
+			}
+			errMsg := fmt.Sprintf("Extract the field %s error: %s", field.rule.Name, err)
+			return nil, errors.New(errMsg)
+		}
+		if value == nil {
+			continue
+		}
+		if !field.rule.List {
+			return value, nil
+		}
+		values = append(values, value)
+	}
+	if !field.rule.List || len(values) == 0 {
+		return nil, nil
+	}
+	return values, nil
+}
+
+func (field *fieldMatcher) value(node *html.Node) (interface{}, error) {
+	if len(field.fields) > 0 {
+		imap, err := extractFields(field.fields, node)
+		if err != nil {
+			return nil, err
+		}
+		return imap, nil
+	}
+	var text string
+	switch field.rule.Mode {
+	case FIELD_MODE_HTML:
+		text = htmlquery.OutputHTML(node, false)
+	case FIELD_MODE_ATTR:
+		if !htmlquery.ExistsAttr(node, field.rule.Attr) {
+			return nil, nil
+		}
+		text = htmlquery.SelectAttr(node, field.rule.Attr)
+	default:
+		text = htmlquery.InnerText(node)
+	}
+	text = strings.TrimSpace(text)
+	if field.regex != nil {
+		match := field.regex.FindStringSubmatch(text)
+		if match == nil {
+			return nil, nil
+		}
+		text = match[0]
+		if len(match) > 1 {
+			text = match[1]
+		}
+	}
+	return convertFieldValue(text, field.rule.Type)
+}
+
+// 把文本转换为字段的类型，空的文本视为没有值。
+func convertFieldValue(text string, fieldType string) (interface{}, error) {
+	if fieldType == "" || fieldType == FIELD_TYPE_STRING {
+		return text, nil
+	}
+	text = strings.Replace(strings.TrimSpace(text), ",", "", -1)
+	if text == "" {
+		return nil, nil
+	}
+	var value interface{}
+	var err error
+	switch fieldType {
+	case FIELD_TYPE_INT:
+		value, err = strconv.ParseInt(text, 10, 64)
+	case FIELD_TYPE_FLOAT:
+		value, err = strconv.ParseFloat(text, 64)
+	case FIELD_TYPE_BOOL:
+		value, err = strconv.ParseBool(text)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Convert %q to %s error: %s\n", text, fieldType, err))
+	}
+	return value, nil
+}
@@ -0,0 +1,108 @@
+package analyzer
+
+import (
+	"reflect"
+	"testing"
+	"webcrawler/base"
+)
+
+const testItemsHtml = `<html><body>
+<div class="item"><h2>First</h2><span class="name">alice</span><span class="author-link">a-link</span>
+  <span class="votes">1,024</span><a class="more" href="/a">more</a>
+  <ul><li><b>x</b><i>1</i></li><li><b>y</b><i>2</i></li></ul></div>
+<div class="item"><h2>Second</h2><span class="name"></span><span class="author-link">bob</span>
+  <span class="votes">7</span></div>
+<div class="item"><span class="name">no title</span></div>
+</body></html>`
+
+const testItemRules = `
+items:
+  - name: entry
+    css: .item
+    fields:
+      - name: title
+        xpath: .//h2
+        required: true
+      - name: nickname
+        css: .name
+        fallback:
+          css: .author-link
+      - name: votes
+        css: .votes
+        type: int
+      - name: more
+        css: a.more
+        attr: href
+        default: none
+      - name: tags
+        css: li
+        list: true
+        fields:
+          - name: key
+            css: b
+          - name: value
+            css: i
+            type: int
+`
+
+func TestItemExtractor(t *testing.T) {
+	rules, err := ParseItemRules([]byte(testItemRules), "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	extractor, err := NewItemExtractor(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items, errs := extractor.Extract(newTestResponse("http://example.com/", "text/html", testItemsHtml))
+	if len(errs) != 1 {
+		t.Fatalf("errs = %v, want the missing title error", errs)
+	}
+	want := []base.Item{
+		{
+			ItemRuleKey: "entry",
+			"title":     "First",
+			"nickname":  "alice",
+			"votes":     int64(1024),
+			"more":      "/a",
+			"tags": []interface{}{
+				map[string]interface{}{"key": "x", "value": int64(1)},
+				map[string]interface{}{"key": "y", "value": int64(2)},
+			},
+		},
+		{
+			ItemRuleKey: "entry",
+			"title":     "Second",
+			"nickname":  "bob",
+			"votes":     int64(7),
+			"more":      "none",
+		},
+	}
+	if !reflect.DeepEqual(items, want) {
+		t.Fatalf("items = %#v, want %#v", items, want)
+	}
+}
+
+func TestItemRulesErrors(t *testing.T) {
+	tests := []struct {
+		format string
+		rules  string
+	}{
+		{"yaml", "items:\n  - css: .a\n    fields:\n      - name: a\n        unknown: 1\n"},
+		{"json", `{"items": [{"css": ".a", "fields": [{"name": "a", "css": ".b", "xpath": "//b"}]}]}`},
+		{"yaml", "items:\n  - css: .a\n    fields:\n      - name: a\n        type: date\n"},
+		{"yaml", "items:\n  - css: '[['\n    fields:\n      - name: a\n"},
+		{"yaml", "items:\n  - css: .a\n    fields:\n      - name: a\n        fallback:\n          css: '[['\n"},
+		{"yaml", "items: []\n"},
+		{"toml", "items = []"},
+	}
+	for _, test := range tests {
+		rules, err := ParseItemRules([]byte(test.rules), test.format)
+		if err == nil {
+			_, err = NewItemExtractor(rules)
+		}
+		if err == nil {
+			t.Errorf("The rules should be rejected: %s", test.rules)
+		}
+	}
+}
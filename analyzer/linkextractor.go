@@ -0,0 +1,280 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 23:16:48
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 23:16:48
+ */
+
+package analyzer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/PuerkitoBio/goquery"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"webcrawler/base"
+)
+
+// 默认提取链接的标签和属性。
+// 默认不提取图片等资源的链接，需要时可以设置Tags为 img、source 等，Attrs为 src、srcset。
+var (
+	DefaultLinkTags  = []string{"a", "area", "link", "iframe"}
+	DefaultLinkAttrs = []string{"href", "src"}
+)
+
+// 链接提取规则，零值表示用默认的标签和属性提取整个文档中的所有链接。
+type LinkRules struct {
+	Allow          []string // 链接需要匹配其中之一的正则表达式，为空时不限制。
+	Deny           []string // 匹配其中之一的链接被忽略，优先于Allow。
+	Tags           []string // 提取链接的标签，为空时使用DefaultLinkTags。
+	Attrs          []string // 提取链接的属性，为空时使用DefaultLinkAttrs。
+	Scope          string   // 只在匹配该CSS选择器的元素中提取，为空时为整个文档。
+	FollowNofollow bool     // 是否提取rel="nofollow"的链接和<meta name="robots" content="nofollow">的页面中的链接。
+}
+
+// 链接提取器。
+// 链接以<base href>（没有时为请求的URL）为基准解析为绝对URL，去掉片段并去重，
+// 只保留http和https的链接。<meta http-equiv="refresh">中的链接总是被提取。
+type LinkExtractor interface {
+	// 提取HTML响应中的链接，不是HTML或状态码不是2xx时返回nil。
+	Extract(httpResp *http.Response) ([]*url.URL, error)
+	// 用于 ParseResponse 的解析函数，为每个链接生成GET请求。
+	Parse(httpResp *http.Response, respDepth uint32) ([]base.Data, []error)
+}
+
+type myLinkExtractor struct {
+	allow          []*regexp.Regexp
+	deny           []*regexp.Regexp
+	selector       string
+	attrs          []string
+	scope          string
+	followNofollow bool
+}
+
+func NewLinkExtractor(rules LinkRules) (LinkExtractor, error) {
+	allow, err := compileLinkPatterns(rules.Allow)
+	if err != nil {
+		return nil, err
+	}
+	deny, err := compileLinkPatterns(rules.Deny)
+	if err != nil {
+		return nil, err
+	}
+	tags := rules.Tags
+	if len(tags) == 0 {
+		tags = DefaultLinkTags
+	}
+	attrs := rules.Attrs
+	if len(attrs) == 0 {
+		attrs = DefaultLinkAttrs
+	}
+	return &myLinkExtractor{
+		allow:          allow,
+		deny:           deny,
+		selector:       strings.Join(tags, ", "),
+		attrs:          attrs,
+		scope:          rules.Scope,
+		followNofollow: rules.FollowNofollow,
+	}, nil
+}
+
+func compileLinkPatterns(patterns []string) ([]*regexp.Regexp, error) {
+	regexps := make([]*regexp.Regexp, 0, len(patterns))
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Invalid link pattern %q: %s\n", pattern, err))
+		}
+		regexps = append(regexps, re)
+	}
+	return regexps, nil
+}
+
+func (le *myLinkExtractor) Extract(httpResp *http.Response) ([]*url.URL, error) {
+	if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 || !htmlResponse(httpResp) {
+		return nil, nil
+	}
+	doc, err := goquery.NewDocumentFromReader(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	baseUrl := httpResp.Request.URL
+	if href, ok := doc.Find("base[href]").First().Attr("href"); ok {
+		if u, err := baseUrl.Parse(strings.TrimSpace(href)); err == nil {
+			baseUrl = u
+		}
+	}
+	nofollow := !le.followNofollow && metaNofollow(doc)
+
+	links := make([]*url.URL, 0)
+	seen := make(map[string]bool)
+	add := func(ref string) {
+		u := le.resolve(baseUrl, ref)
+		if u == nil || seen[u.String()] {
+			return
+		}
+		seen[u.String()] = true
+		links = append(links, u)
+	}
+
+	doc.Find("meta[http-equiv]").Each(func(index int, sel *goquery.Selection) {
+		equiv, _ := sel.Attr("http-equiv")
+		content, _ := sel.Attr("content")
+		if strings.EqualFold(strings.TrimSpace(equiv), "refresh") {
+			if ref := refreshUrl(content); ref != "" {
+				add(ref)
+			}
+		}
+	})
+	if nofollow {
+		return links, nil
+	}
+
+	scope := doc.Selection
+	if le.scope != "" {
+		scope = doc.Find(le.scope)
+	}
+	elements := scope.Find(le.selector).AddSelection(scope.Filter(le.selector))
+	elements.Each(func(index int, sel *goquery.Selection) {
+		if !le.followNofollow && hasToken(sel.AttrOr("rel", ""), "nofollow") {
+			return
+		}
+		for _, attr := range le.attrs {
+			value, ok := sel.Attr(attr)
+			if !ok {
+				continue
+			}
+			if strings.EqualFold(attr, "srcset") {
+				for _, ref := range srcsetUrls(value) {
+					add(ref)
+				}
+			} else {
+				add(value)
+			}
+		}
+	})
+	return links, nil
+}
+
+func (le *myLinkExtractor) Parse(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
+	links, err := le.Extract(httpResp)
+	if err != nil {
+		return nil, []error{err}
+	}
+	dataList := make([]base.Data, 0, len(links))
+	errs := make([]error, 0)
+	for _, link := range links {
+		httpReq, err := http.NewRequest("GET", link.String(), nil)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		dataList = append(dataList, base.NewRequest(httpReq, respDepth))
+	}
+	return dataList, errs
+}
+
+// 把链接解析为绝对URL，不符合规则时返回nil。
+func (le *myLinkExtractor) resolve(baseUrl *url.URL, ref string) *url.URL {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || strings.HasPrefix(ref, "#") {
+		return nil
+	}
+	u, err := baseUrl.Parse(ref)
+	if err != nil {
+		return nil
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	link := u.String()
+	for _, re := range le.deny {
+		if re.MatchString(link) {
+			return nil
+		}
+	}
+	if len(le.allow) == 0 {
+		return u
+	}
+	for _, re := range le.allow {
+		if re.MatchString(link) {
+			return u
+		}
+	}
+	return nil
+}
+
+func htmlResponse(httpResp *http.Response) bool {
+	contentType := httpResp.Header.Get("Content-Type")
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+func metaNofollow(doc *goquery.Document) bool {
+	nofollow := false
+	doc.Find("meta[name]").Each(func(index int, sel *goquery.Selection) {
+		name, _ := sel.Attr("name")
+		if strings.EqualFold(name, "robots") && hasToken(sel.AttrOr("content", ""), "nofollow") {
+			nofollow = true
+		}
+	})
+	return nofollow
+}
+
+// 值中是否包含不区分大小写的token，token之间用空白或逗号分隔。
+func hasToken(value string, token string) bool {
+	fields := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == '\f'
+	})
+	for _, field := range fields {
+		if strings.EqualFold(field, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// 解析<meta http-equiv="refresh" content="5; url=...">中的链接。
+func refreshUrl(content string) string {
+	i := strings.IndexAny(content, ";,")
+	if i < 0 {
+		return ""
+	}
+	rest := strings.TrimSpace(content[i+1:])
+	if len(rest) < 3 || !strings.EqualFold(rest[:3], "url") {
+		return ""
+	}
+	rest = strings.TrimSpace(rest[3:])
+	if !strings.HasPrefix(rest, "=") {
+		return ""
+	}
+	rest = strings.TrimSpace(rest[1:])
+	if len(rest) > 0 && (rest[0] == '\'' || rest[0] == '"') {
+		if j := strings.IndexByte(rest[1:], rest[0]); j >= 0 {
+			return rest[1 : j+1]
+		}
+		return rest[1:]
+	}
+	return rest
+}
+
+// 解析srcset属性中的链接，每一项是URL和可选的描述符，项之间用逗号分隔。
+func srcsetUrls(srcset string) []string {
+	refs := make([]string, 0)
+	for _, candidate := range strings.Split(srcset, ",") {
+		fields := strings.Fields(candidate)
+		if len(fields) > 0 {
+			refs = append(refs, fields[0])
+		}
+	}
+	return refs
+}
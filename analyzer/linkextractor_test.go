@@ -0,0 +1,124 @@
+package analyzer
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"webcrawler/base"
+)
+
+const testLinksHtml = `<html><head>
+<base href="http://example.com/dir/">
+<meta http-equiv="Refresh" content="5; url='/refresh'">
+<link rel="stylesheet" href="style.css">
+</head><body>
+<a href="a.html#top">A</a>
+<a href="a.html">A again</a>
+<a href="/b?x=1">B</a>
+<a href="javascript:void(0)">JS</a>
+<a href="mailto:a@example.com">Mail</a>
+<a href="#section">Fragment</a>
+<a href="/private" rel="external nofollow">Private</a>
+<div class="nav"><a href="http://other.com/c">C</a><area href="/d"></div>
+<iframe src="/frame"></iframe>
+<img src="/img.png" srcset="/small.png 1x, /large.png 2x">
+</body></html>`
+
+func extractLinks(t *testing.T, rules LinkRules, contentType string, body string) []string {
+	extractor, err := NewLinkExtractor(rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	links, err := extractor.Extract(newTestResponse("http://example.com/page", contentType, body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	urls := make([]string, 0, len(links))
+	for _, link := range links {
+		urls = append(urls, link.String())
+	}
+	return urls
+}
+
+func TestLinkExtractor(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules LinkRules
+		want  []string
+	}{
+		{"default", LinkRules{}, []string{
+			"http://example.com/refresh",
+			"http://example.com/dir/style.css",
+			"http://example.com/dir/a.html",
+			"http://example.com/b?x=1",
+			"http://other.com/c",
+			"http://example.com/d",
+			"http://example.com/frame",
+		}},
+		{"nofollow", LinkRules{Tags: []string{"a"}, FollowNofollow: true}, []string{
+			"http://example.com/refresh",
+			"http://example.com/dir/a.html",
+			"http://example.com/b?x=1",
+			"http://example.com/private",
+			"http://other.com/c",
+		}},
+		{"allow and deny", LinkRules{Allow: []string{`example\.com/`}, Deny: []string{`\.css$`, `/refresh$`}}, []string{
+			"http://example.com/dir/a.html",
+			"http://example.com/b?x=1",
+			"http://example.com/d",
+			"http://example.com/frame",
+		}},
+		{"scope", LinkRules{Scope: ".nav"}, []string{
+			"http://example.com/refresh",
+			"http://other.com/c",
+			"http://example.com/d",
+		}},
+		{"srcset", LinkRules{Tags: []string{"img"}, Attrs: []string{"src", "srcset"}}, []string{
+			"http://example.com/refresh",
+			"http://example.com/img.png",
+			"http://example.com/small.png",
+			"http://example.com/large.png",
+		}},
+	}
+	for _, test := range tests {
+		links := extractLinks(t, test.rules, "text/html; charset=utf-8", testLinksHtml)
+		if !reflect.DeepEqual(links, test.want) {
+			t.Errorf("%s: links = %v, want %v", test.name, links, test.want)
+		}
+	}
+}
+
+func TestLinkExtractorMetaNofollow(t *testing.T) {
+	body := `<html><head><meta name="robots" content="noindex, nofollow"></head>
+<body><a href="/a">A</a></body></html>`
+	if links := extractLinks(t, LinkRules{}, "text/html", body); len(links) != 0 {
+		t.Fatalf("links = %v, want none", links)
+	}
+	if links := extractLinks(t, LinkRules{FollowNofollow: true}, "text/html", body); len(links) != 1 {
+		t.Fatalf("links = %v, want one", links)
+	}
+}
+
+func TestLinkExtractorNonHtml(t *testing.T) {
+	if links := extractLinks(t, LinkRules{}, "application/json", `{"a": "<a href='/a'>"}`); len(links) != 0 {
+		t.Fatalf("links = %v, want none", links)
+	}
+}
+
+func TestLinkExtractorParse(t *testing.T) {
+	extractor, err := NewLinkExtractor(LinkRules{Tags: []string{"a"}, Allow: []string{`/b`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataList, errs := extractor.Parse(newTestResponse("http://example.com/page", "text/html", testLinksHtml), 2)
+	if len(errs) != 0 || len(dataList) != 1 {
+		t.Fatalf("Parse() = (%v, %v)", dataList, errs)
+	}
+	req, ok := dataList[0].(*base.Request)
+	if !ok || req.HttpReq().Method != http.MethodGet || req.HttpReq().URL.String() != "http://example.com/b?x=1" || req.Depth() != 2 {
+		t.Fatalf("Unexpected request: %#v", dataList[0])
+	}
+	if _, err := NewLinkExtractor(LinkRules{Allow: []string{"("}}); err == nil {
+		t.Fatalf("An invalid pattern should be rejected")
+	}
+}
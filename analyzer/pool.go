@@ -17,8 +17,11 @@ import (
 type AnalyzerPool interface {
 	Take() (Analyzer, error)
 	Return(analyzer Analyzer) error
+	Resize(total uint32) error
 	Total() uint32
 	Used() uint32
+	// 关闭池，等待中的 Take 会返回错误。
+	Close()
 }
 
 type myAnalyzerPool struct {
@@ -58,6 +61,10 @@ func (ap *myAnalyzerPool) Return(analyzer Analyzer) error {
 	return ap.pool.Return(analyzer)
 }
 
+func (ap *myAnalyzerPool) Resize(total uint32) error {
+	return ap.pool.Resize(total)
+}
+
 func (ap *myAnalyzerPool) Total() uint32 {
 	return ap.pool.Total()
 }
@@ -65,3 +72,7 @@ func (ap *myAnalyzerPool) Total() uint32 {
 func (ap *myAnalyzerPool) Used() uint32 {
 	return ap.pool.Used()
 }
+
+func (ap *myAnalyzerPool) Close() {
+	ap.pool.Close()
+}
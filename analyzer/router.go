@@ -0,0 +1,133 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-19 00:21:33
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-19 00:21:33
+ */
+
+package analyzer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"webcrawler/base"
+)
+
+// 解析函数的路由规则，设置的条件都满足时匹配，没有设置条件的规则匹配所有的响应。
+type Route struct {
+	Pattern     string // 请求的URL需要匹配的正则表达式。
+	ContentType string // 响应的媒体类型，可以用 text/* 的形式匹配一类。
+	Tag         string // 请求的标签，参见 base.Request.SetTag。
+	Parsers     []ParseResponse
+}
+
+// 解析函数的路由器，把响应只交给第一个匹配的规则中的解析函数。
+type Router interface {
+	// 获取处理响应的解析函数，没有匹配的规则时返回nil。
+	Route(httpResp *http.Response) []ParseResponse
+	// 用于 ParseResponse 的解析函数，依次调用匹配的规则中的解析函数。
+	Parse(httpResp *http.Response, respDepth uint32) ([]base.Data, []error)
+}
+
+type route struct {
+	pattern     *regexp.Regexp
+	contentType string
+	tag         string
+	parsers     []ParseResponse
+}
+
+type myRouter struct {
+	routes []*route
+}
+
+// 创建路由器，规则按给定的顺序匹配。
+func NewRouter(routes ...Route) (Router, error) {
+	if len(routes) == 0 {
+		return nil, errors.New("The route list is empty!\n")
+	}
+	router := &myRouter{routes: make([]*route, 0, len(routes))}
+	for i, r := range routes {
+		if len(r.Parsers) == 0 {
+			return nil, errors.New(fmt.Sprintf("The parser list of the %dth route is empty!\n", i))
+		}
+		for j, parser := range r.Parsers {
+			if parser == nil {
+				return nil, errors.New(fmt.Sprintf("The %dth parser of the %dth route is invalid!\n", j, i))
+			}
+		}
+		rt := &route{
+			contentType: strings.ToLower(r.ContentType),
+			tag:         r.Tag,
+			parsers:     r.Parsers,
+		}
+		if r.Pattern != "" {
+			pattern, err := regexp.Compile(r.Pattern)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("Invalid pattern %q of the %dth route: %s\n", r.Pattern, i, err))
+			}
+			rt.pattern = pattern
+		}
+		router.routes = append(router.routes, rt)
+	}
+	return router, nil
+}
+
+func (router *myRouter) Route(httpResp *http.Response) []ParseResponse {
+	for _, rt := range router.routes {
+		if rt.match(httpResp) {
+			return rt.parsers
+		}
+	}
+	return nil
+}
+
+func (rt *route) match(httpResp *http.Response) bool {
+	if rt.pattern != nil && !rt.pattern.MatchString(httpResp.Request.URL.String()) {
+		return false
+	}
+	if rt.tag != "" && rt.tag != base.RequestTag(httpResp.Request) {
+		return false
+	}
+	if rt.contentType != "" {
+		mediaType, _, err := mime.ParseMediaType(httpResp.Header.Get("Content-Type"))
+		if err != nil {
+			return false
+		}
+		if strings.HasSuffix(rt.contentType, "/*") {
+			return strings.HasPrefix(mediaType, rt.contentType[:len(rt.contentType)-1])
+		}
+		return mediaType == rt.contentType
+	}
+	return true
+}
+
+func (router *myRouter) Parse(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
+	parsers := router.Route(httpResp)
+	if len(parsers) == 0 {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, []error{err}
+	}
+	dataList := make([]base.Data, 0)
+	errs := make([]error, 0)
+	ctx := httpResp.Request.Context()
+	for _, parser := range parsers {
+		if ctx.Err() != nil {
+			break
+		}
+		// 每个解析函数都从头读取响应体。
+		httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+		pDataList, pErrs := parser(httpResp, respDepth)
+		dataList = append(dataList, pDataList...)
+		errs = append(errs, pErrs...)
+	}
+	return dataList, errs
+}
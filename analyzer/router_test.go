@@ -0,0 +1,100 @@
+package analyzer
+
+import (
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"webcrawler/base"
+)
+
+// 返回一个把名字和响应体作为条目的解析函数。
+func namedParser(name string) ParseResponse {
+	return func(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
+		body, err := ioutil.ReadAll(httpResp.Body)
+		if err != nil {
+			return nil, []error{err}
+		}
+		item := base.Item{"parser": name, "body": string(body)}
+		return []base.Data{&item}, nil
+	}
+}
+
+func parserNames(dataList []base.Data) []string {
+	names := make([]string, 0, len(dataList))
+	for _, data := range dataList {
+		names = append(names, (*data.(*base.Item))["parser"].(string))
+	}
+	return names
+}
+
+func TestRouterRoute(t *testing.T) {
+	router, err := NewRouter(
+		Route{Tag: "detail", Parsers: []ParseResponse{namedParser("tag")}},
+		Route{Pattern: `/question/\d+$`, ContentType: "text/html", Parsers: []ParseResponse{namedParser("question")}},
+		Route{ContentType: "image/*", Parsers: []ParseResponse{namedParser("image")}},
+		Route{Pattern: `^https://`, Parsers: []ParseResponse{namedParser("https")}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tagged := newTestResponse("http://example.com/question/1", "text/html", "")
+	req := base.NewRequest(tagged.Request, 0)
+	req.SetTag("detail")
+	tagged.Request = req.HttpReq()
+	cases := []struct {
+		name     string
+		httpResp *http.Response
+		want     string
+	}{
+		{"tag first", tagged, "tag"},
+		{"pattern and content type", newTestResponse("http://example.com/question/1", "text/html; charset=utf-8", ""), "question"},
+		{"content type mismatch", newTestResponse("http://example.com/question/1", "application/json", ""), ""},
+		{"wildcard content type", newTestResponse("http://example.com/a.png", "image/png", ""), "image"},
+		{"invalid content type", newTestResponse("http://example.com/a.png", "image/png;;", ""), ""},
+		{"pattern only", newTestResponse("https://example.com/question/1", "application/json", ""), "https"},
+	}
+	for _, c := range cases {
+		dataList, errs := router.Parse(c.httpResp, 0)
+		if len(errs) > 0 {
+			t.Fatalf("%s: Parse() errors: %v", c.name, errs)
+		}
+		got := ""
+		if names := parserNames(dataList); len(names) > 0 {
+			got = names[0]
+		}
+		if got != c.want {
+			t.Errorf("%s: routed to %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestRouterParseRereadsBody(t *testing.T) {
+	router, err := NewRouter(Route{Parsers: []ParseResponse{namedParser("a"), namedParser("b")}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataList, errs := router.Parse(newTestResponse("http://example.com/", "text/html", "<html></html>"), 0)
+	if len(errs) > 0 || len(dataList) != 2 {
+		t.Fatalf("Parse() = %v, %v", dataList, errs)
+	}
+	for _, data := range dataList {
+		if body := (*data.(*base.Item))["body"]; body != "<html></html>" {
+			t.Fatalf("The parser read %q", body)
+		}
+	}
+}
+
+func TestNewRouterErrors(t *testing.T) {
+	if _, err := NewRouter(); err == nil {
+		t.Errorf("NewRouter() without routes should fail")
+	}
+	if _, err := NewRouter(Route{Pattern: "x"}); err == nil {
+		t.Errorf("NewRouter() with an empty parser list should fail")
+	}
+	if _, err := NewRouter(Route{Parsers: []ParseResponse{nil}}); err == nil {
+		t.Errorf("NewRouter() with a nil parser should fail")
+	}
+	if _, err := NewRouter(Route{Pattern: "(", Parsers: []ParseResponse{namedParser("a")}}); err == nil {
+		t.Errorf("NewRouter() with an invalid pattern should fail")
+	}
+}
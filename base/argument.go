@@ -8,8 +8,10 @@
 package base
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
+	"time"
 )
 
 type Args interface {
@@ -36,17 +38,21 @@ func NewChannelArgs(reqChanLen uint, respChanLen uint, itemChanLen uint, errorCh
 }
 
 func (args *ChannelArgs) Check() error {
+	var buffer bytes.Buffer
 	if args.reqChanLen == 0 {
-		return errors.New("The request channel max length (capacity) can not be 0!\n")
+		buffer.WriteString("The request channel max length (capacity) can not be 0!\n")
 	}
 	if args.respChanLen == 0 {
-		return errors.New("The response channel max length (capacity) can not be 0!\n")
+		buffer.WriteString("The response channel max length (capacity) can not be 0!\n")
 	}
 	if args.itemChanLen == 0 {
-		return errors.New("The item channel max length (capacity) can not be 0!\n")
+		buffer.WriteString("The item channel max length (capacity) can not be 0!\n")
 	}
 	if args.errorChanLen == 0 {
-		return errors.New("The error channel max length (capacity) can not be 0!\n")
+		buffer.WriteString("The error channel max length (capacity) can not be 0!\n")
+	}
+	if buffer.Len() > 0 {
+		return errors.New(buffer.String())
 	}
 	return nil
 }
@@ -82,11 +88,15 @@ func NewPoolBaseArgs(pageDownloaderPoolSize uint32, analyzerPoolSize uint32) Poo
 }
 
 func (args *PoolBaseArgs) Check() error {
+	var buffer bytes.Buffer
 	if args.pageDownloaderPoolSize == 0 {
-		return errors.New("The page downloader pool size can not be 0!\n")
+		buffer.WriteString("The page downloader pool size can not be 0!\n")
 	}
 	if args.analyzerPoolSize == 0 {
-		return errors.New("The analyzer pool size can not be 0!\n")
+		buffer.WriteString("The analyzer pool size can not be 0!\n")
+	}
+	if buffer.Len() > 0 {
+		return errors.New(buffer.String())
 	}
 	return nil
 }
@@ -111,3 +121,157 @@ func (args *PoolBaseArgs) PageDownloaderPoolSize() uint32 {
 func (args *PoolBaseArgs) AnalyzerPoolSize() uint32 {
 	return args.analyzerPoolSize
 }
+
+type HostLimitArgs struct {
+	crawlDelay        time.Duration
+	maxInFlightHost   uint32
+	maxInFlightDomain uint32
+	description       string
+}
+
+func NewHostLimitArgs(crawlDelay time.Duration, maxInFlightHost uint32, maxInFlightDomain uint32) HostLimitArgs {
+	return HostLimitArgs{crawlDelay: crawlDelay, maxInFlightHost: maxInFlightHost, maxInFlightDomain: maxInFlightDomain}
+}
+
+func (args *HostLimitArgs) Check() error {
+	if args.crawlDelay < 0 {
+		return errors.New("The crawl delay can not be negative!\n")
+	}
+	return nil
+}
+
+var hostLimitArgsTemplate string = "{ crawlDelay: %s," +
+	" maxInFlightHost: %d, maxInFlightDomain: %d }"
+
+func (args *HostLimitArgs) String() string {
+	if args.description == "" {
+		args.description =
+			fmt.Sprintf(hostLimitArgsTemplate,
+				args.crawlDelay,
+				args.maxInFlightHost,
+				args.maxInFlightDomain)
+	}
+	return args.description
+}
+
+func (args *HostLimitArgs) CrawlDelay() time.Duration {
+	return args.crawlDelay
+}
+
+// 0 表示不限制。
+func (args *HostLimitArgs) MaxInFlightHost() uint32 {
+	return args.maxInFlightHost
+}
+
+// 0 表示不限制。
+func (args *HostLimitArgs) MaxInFlightDomain() uint32 {
+	return args.maxInFlightDomain
+}
+
+type RobotsArgs struct {
+	userAgent   string
+	cacheTTL    time.Duration
+	description string
+}
+
+func NewRobotsArgs(userAgent string, cacheTTL time.Duration) RobotsArgs {
+	return RobotsArgs{userAgent: userAgent, cacheTTL: cacheTTL}
+}
+
+func (args *RobotsArgs) Check() error {
+	if args.userAgent == "" {
+		return errors.New("The robots user-agent can not be empty!\n")
+	}
+	if args.cacheTTL < 0 {
+		return errors.New("The robots cache TTL can not be negative!\n")
+	}
+	return nil
+}
+
+var robotsArgsTemplate string = "{ userAgent: %s, cacheTTL: %s }"
+
+func (args *RobotsArgs) String() string {
+	if args.description == "" {
+		args.description = fmt.Sprintf(robotsArgsTemplate, args.userAgent, args.cacheTTL)
+	}
+	return args.description
+}
+
+func (args *RobotsArgs) UserAgent() string {
+	return args.userAgent
+}
+
+// 0 表示永不过期。
+func (args *RobotsArgs) CacheTTL() time.Duration {
+	return args.cacheTTL
+}
+
+type AutoThrottleArgs struct {
+	targetLatency  time.Duration
+	maxConcurrency uint32
+	minDelay       time.Duration
+	maxDelay       time.Duration
+	description    string
+}
+
+func NewAutoThrottleArgs(targetLatency time.Duration, maxConcurrency uint32, minDelay time.Duration, maxDelay time.Duration) AutoThrottleArgs {
+	return AutoThrottleArgs{
+		targetLatency:  targetLatency,
+		maxConcurrency: maxConcurrency,
+		minDelay:       minDelay,
+		maxDelay:       maxDelay,
+	}
+}
+
+func (args *AutoThrottleArgs) Check() error {
+	var buffer bytes.Buffer
+	if args.targetLatency <= 0 {
+		buffer.WriteString("The target latency of auto throttle must be positive!\n")
+	}
+	if args.maxConcurrency == 0 {
+		buffer.WriteString("The max concurrency of auto throttle can not be 0!\n")
+	}
+	if args.minDelay < 0 {
+		buffer.WriteString("The min delay of auto throttle can not be negative!\n")
+	}
+	if args.maxDelay < args.minDelay {
+		buffer.WriteString("The max delay of auto throttle can not be less than the min delay!\n")
+	}
+	if buffer.Len() > 0 {
+		return errors.New(buffer.String())
+	}
+	return nil
+}
+
+var autoThrottleArgsTemplate string = "{ targetLatency: %s, maxConcurrency: %d," +
+	" minDelay: %s, maxDelay: %s }"
+
+func (args *AutoThrottleArgs) String() string {
+	if args.description == "" {
+		args.description =
+			fmt.Sprintf(autoThrottleArgsTemplate,
+				args.targetLatency,
+				args.maxConcurrency,
+				args.minDelay,
+				args.maxDelay)
+	}
+	return args.description
+}
+
+// 期望的响应延迟，超过时视为目标站点已经过载。
+func (args *AutoThrottleArgs) TargetLatency() time.Duration {
+	return args.targetLatency
+}
+
+// 每个主机的最大并发数。
+func (args *AutoThrottleArgs) MaxConcurrency() uint32 {
+	return args.maxConcurrency
+}
+
+func (args *AutoThrottleArgs) MinDelay() time.Duration {
+	return args.minDelay
+}
+
+func (args *AutoThrottleArgs) MaxDelay() time.Duration {
+	return args.maxDelay
+}
@@ -0,0 +1,231 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 15:50:03
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 15:50:03
+ */
+
+package base
+
+import (
+	"bytes"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// 默认丢弃的跟踪参数，以 "*" 结尾的表示前缀。
+var DefaultTrackingParams = []string{
+	"utm_*",
+	"gclid",
+	"fbclid",
+	"msclkid",
+	"yclid",
+	"mc_cid",
+	"mc_eid",
+	"_hsenc",
+	"_hsmi",
+	"spm",
+}
+
+type CanonicalOptions struct {
+	LowercaseHost     bool     // 将协议和主机名转为小写。
+	StripDefaultPort  bool     // 去掉默认端口（http:80, https:443）。
+	StripFragment     bool     // 去掉片段（#之后的部分）。
+	RemoveDotSegments bool     // 解析路径中的 "." 和 ".."。
+	NormalizeEscapes  bool     // 解码非保留字符的百分号编码，其余编码统一为大写。
+	SortQuery         bool     // 按参数名排序查询参数。
+	DropParams        []string // 要丢弃的查询参数。
+}
+
+func DefaultCanonicalOptions() CanonicalOptions {
+	return CanonicalOptions{
+		LowercaseHost:     true,
+		StripDefaultPort:  true,
+		StripFragment:     true,
+		RemoveDotSegments: true,
+		NormalizeEscapes:  true,
+		SortQuery:         true,
+		DropParams:        DefaultTrackingParams,
+	}
+}
+
+// URL规范化器，用于在去重之前得到URL的规范形式。
+type Canonicalizer interface {
+	Canonicalize(reqUrl *url.URL) string
+}
+
+type myCanonicalizer struct {
+	options CanonicalOptions
+}
+
+func NewCanonicalizer(options CanonicalOptions) Canonicalizer {
+	return &myCanonicalizer{options: options}
+}
+
+func (canon *myCanonicalizer) Canonicalize(reqUrl *url.URL) string {
+	if reqUrl == nil {
+		return ""
+	}
+	opts := canon.options
+	u := *reqUrl
+	if opts.LowercaseHost {
+		u.Scheme = strings.ToLower(u.Scheme)
+		u.Host = strings.ToLower(u.Host)
+	}
+	if opts.StripDefaultPort {
+		scheme := strings.ToLower(u.Scheme)
+		if (scheme == "http" && strings.HasSuffix(u.Host, ":80")) ||
+			(scheme == "https" && strings.HasSuffix(u.Host, ":443")) {
+			u.Host = u.Host[:strings.LastIndex(u.Host, ":")]
+		}
+	}
+	if opts.StripFragment {
+		u.Fragment = ""
+		u.RawFragment = ""
+	}
+
+	path := u.EscapedPath()
+	if opts.NormalizeEscapes {
+		path = normalizeEscapes(path)
+	}
+	if opts.RemoveDotSegments {
+		path = removeDotSegments(path)
+	}
+	if path == "" && u.Host != "" {
+		path = "/"
+	}
+	u.Path, u.RawPath = "", ""
+	if unescaped, err := url.PathUnescape(path); err == nil {
+		u.Path = unescaped
+		u.RawPath = path
+	}
+
+	query := u.RawQuery
+	if query != "" {
+		params := strings.Split(query, "&")
+		kept := make([]string, 0, len(params))
+		for _, param := range params {
+			if param == "" {
+				continue
+			}
+			if opts.NormalizeEscapes {
+				param = normalizeEscapes(param)
+			}
+			if canon.dropped(param) {
+				continue
+			}
+			kept = append(kept, param)
+		}
+		if opts.SortQuery {
+			sort.SliceStable(kept, func(i, j int) bool {
+				return paramName(kept[i]) < paramName(kept[j])
+			})
+		}
+		u.RawQuery = strings.Join(kept, "&")
+	}
+	// 空的查询串和没有查询串是同一个地址。
+	u.ForceQuery = false
+	return u.String()
+}
+
+func paramName(param string) string {
+	if index := strings.Index(param, "="); index >= 0 {
+		return param[:index]
+	}
+	return param
+}
+
+func (canon *myCanonicalizer) dropped(param string) bool {
+	name, err := url.QueryUnescape(paramName(param))
+	if err != nil {
+		name = paramName(param)
+	}
+	name = strings.ToLower(name)
+	for _, pattern := range canon.options.DropParams {
+		pattern = strings.ToLower(pattern)
+		if strings.HasSuffix(pattern, "*") {
+			if strings.HasPrefix(name, pattern[:len(pattern)-1]) {
+				return true
+			}
+		} else if name == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+func isUnreserved(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+func unhex(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
+
+// 解码非保留字符的百分号编码，并将其余编码的十六进制数字转为大写。
+func normalizeEscapes(s string) string {
+	if strings.IndexByte(s, '%') < 0 {
+		return s
+	}
+	var buffer bytes.Buffer
+	for i := 0; i < len(s); i++ {
+		if s[i] == '%' && i+2 < len(s) {
+			hi, ok1 := unhex(s[i+1])
+			lo, ok2 := unhex(s[i+2])
+			if ok1 && ok2 {
+				c := hi<<4 | lo
+				if isUnreserved(c) {
+					buffer.WriteByte(c)
+				} else {
+					buffer.WriteString(strings.ToUpper(s[i : i+3]))
+				}
+				i += 2
+				continue
+			}
+		}
+		buffer.WriteByte(s[i])
+	}
+	return buffer.String()
+}
+
+// 按照 RFC 3986 5.2.4 解析路径中的点段。
+func removeDotSegments(path string) string {
+	if !strings.Contains(path, ".") {
+		return path
+	}
+	segments := strings.Split(path, "/")
+	output := make([]string, 0, len(segments))
+	for i, segment := range segments {
+		last := i == len(segments)-1
+		switch segment {
+		case ".":
+			if last {
+				output = append(output, "")
+			}
+		case "..":
+			if len(output) > 1 {
+				output = output[:len(output)-1]
+			}
+			if last {
+				output = append(output, "")
+			}
+		default:
+			output = append(output, segment)
+		}
+	}
+	result := strings.Join(output, "/")
+	if strings.HasPrefix(path, "/") && !strings.HasPrefix(result, "/") {
+		result = "/" + result
+	}
+	return result
+}
@@ -0,0 +1,42 @@
+package base
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	canon := NewCanonicalizer(DefaultCanonicalOptions())
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{"HTTP://Example.COM:80/a/./b/../c", "http://example.com/a/c"},
+		{"https://example.com:443", "https://example.com/"},
+		{"http://a.com/x#frag", "http://a.com/x"},
+		{"http://a.com/x?", "http://a.com/x"},
+		{"http://a.com/x?utm_source=z", "http://a.com/x"},
+		{"http://a.com/x?&&", "http://a.com/x"},
+		{"http://a.com/x?b=2&a=1&gclid=3", "http://a.com/x?a=1&b=2"},
+		{"http://a.com/%7euser/%2f?q=%e4", "http://a.com/~user/%2F?q=%E4"},
+	}
+	for _, c := range cases {
+		u, err := url.Parse(c.raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := canon.Canonicalize(u); got != c.want {
+			t.Errorf("Canonicalize(%q) = %q, want %q", c.raw, got, c.want)
+		}
+	}
+}
+
+func TestCanonicalizeEmptyQuery(t *testing.T) {
+	canon := NewCanonicalizer(CanonicalOptions{})
+	withQuery, _ := url.Parse("http://a.com/x?")
+	withoutQuery, _ := url.Parse("http://a.com/x")
+	if canon.Canonicalize(withQuery) != canon.Canonicalize(withoutQuery) {
+		t.Fatalf("%q and %q should have the same canonical form",
+			withQuery.String(), withoutQuery.String())
+	}
+}
@@ -0,0 +1,185 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 22:41:37
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 22:41:37
+ */
+
+package base
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html/charset"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// 记录响应体原来的字符集的响应头，解析函数得到的响应体已被转换为UTF-8。
+const CharsetHeader = "X-Webcrawler-Charset"
+
+const (
+	charsetPrescanSize = 1024      // 查找<meta>声明的长度。
+	charsetSniffSize   = 64 * 1024 // 推测字符集时使用的长度。
+)
+
+var boms = []struct {
+	bom     []byte
+	charset string
+}{
+	{[]byte{0xEF, 0xBB, 0xBF}, "utf-8"},
+	{[]byte{0xFE, 0xFF}, "utf-16be"},
+	{[]byte{0xFF, 0xFE}, "utf-16le"},
+}
+
+var metaCharsetRegexp = regexp.MustCompile(`(?is)<meta[^>]+charset\s*=\s*["']?\s*([a-z0-9_:.+-]+)`)
+
+// 推测字符集时依次尝试的多字节字符集。
+var sniffCharsets = []string{"gb18030", "big5", "shift_jis"}
+
+// 检测响应体的字符集，依次使用BOM、Content-Type头、<meta>声明和内容推测。
+// 返回规范的字符集名称（如 utf-8、gbk），不是文本时返回空字符串。
+func DetectCharset(contentType string, body []byte) string {
+	if !textual(contentType, body) {
+		return ""
+	}
+	if name, _ := bomCharset(body); name != "" {
+		return name
+	}
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		if name := lookupCharset(params["charset"]); name != "" {
+			return name
+		}
+	}
+	prescan := body
+	if len(prescan) > charsetPrescanSize {
+		prescan = prescan[:charsetPrescanSize]
+	}
+	if match := metaCharsetRegexp.FindSubmatch(prescan); match != nil {
+		if name := lookupCharset(string(match[1])); name != "" {
+			return name
+		}
+	}
+	return sniffCharset(body)
+}
+
+// 把name字符集的内容转换为UTF-8，同时去掉BOM。
+func DecodeCharset(body []byte, name string) ([]byte, error) {
+	if bomName, size := bomCharset(body); bomName == name {
+		body = body[size:]
+	}
+	if name == "" || name == "utf-8" {
+		return body, nil
+	}
+	enc, _ := charset.Lookup(name)
+	if enc == nil {
+		return nil, errors.New(fmt.Sprintf("Unsupported charset %q!\n", name))
+	}
+	return enc.NewDecoder().Bytes(body)
+}
+
+// 是否是可以转换字符集的文本内容。
+func textual(contentType string, body []byte) bool {
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	switch {
+	case strings.HasPrefix(mediaType, "text/"),
+		strings.HasSuffix(mediaType, "/xml"), strings.HasSuffix(mediaType, "+xml"),
+		strings.HasSuffix(mediaType, "/json"), strings.HasSuffix(mediaType, "+json"),
+		strings.HasSuffix(mediaType, "javascript"):
+		return true
+	}
+	return false
+}
+
+func bomCharset(body []byte) (string, int) {
+	for _, b := range boms {
+		if bytes.HasPrefix(body, b.bom) {
+			return b.charset, len(b.bom)
+		}
+	}
+	return "", 0
+}
+
+func lookupCharset(label string) string {
+	if label == "" {
+		return ""
+	}
+	_, name := charset.Lookup(label)
+	return name
+}
+
+// 在没有声明时推测字符集。
+// 合法的UTF-8视为utf-8；高位字节大多单独出现时视为单字节的windows-1252（Latin-1），
+// 否则选择转换错误最少的多字节字符集。
+func sniffCharset(body []byte) string {
+	if len(body) > charsetSniffSize {
+		body = body[:charsetSniffSize]
+	}
+	// 去掉末尾可能被截断的字符。
+	for i := len(body) - 1; i >= 0 && i > len(body)-4; i-- {
+		if body[i] < utf8.RuneSelf {
+			break
+		}
+		if utf8.RuneStart(body[i]) {
+			body = body[:i]
+			break
+		}
+	}
+	if utf8.Valid(body) {
+		return "utf-8"
+	}
+	var high, paired int
+	for i, b := range body {
+		if b < utf8.RuneSelf {
+			continue
+		}
+		high++
+		if (i > 0 && body[i-1] >= utf8.RuneSelf) || (i+1 < len(body) && body[i+1] >= utf8.RuneSelf) {
+			paired++
+		}
+	}
+	if paired*2 < high {
+		return "windows-1252"
+	}
+	best, bestScore := "windows-1252", 0
+	for i, label := range sniffCharsets {
+		enc, name := charset.Lookup(label)
+		decoded, err := enc.NewDecoder().Bytes(body)
+		if err != nil {
+			continue
+		}
+		if score := charsetScore(decoded); i == 0 || score > bestScore {
+			best, bestScore = name, score
+		}
+	}
+	return best
+}
+
+// 转换结果的可信程度。
+// 转换错误和不常见的字符减分，CJK标点、假名和全角字符加分，汉字不计分。
+func charsetScore(text []byte) int {
+	score := 0
+	for _, r := range string(text) {
+		switch {
+		case r < utf8.RuneSelf:
+		case r == utf8.RuneError:
+			score -= 10
+		case r >= 0x3000 && r <= 0x30FF, r >= 0xFF01 && r <= 0xFF5E:
+			score++
+		case r >= 0x4E00 && r <= 0x9FFF:
+		default:
+			score--
+		}
+	}
+	return score
+}
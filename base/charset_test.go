@@ -0,0 +1,67 @@
+package base
+
+import (
+	"bytes"
+	"golang.org/x/net/html/charset"
+	"testing"
+)
+
+func encode(t *testing.T, name string, text string) []byte {
+	enc, _ := charset.Lookup(name)
+	if enc == nil {
+		t.Fatalf("Unknown charset %q", name)
+	}
+	encoded, err := enc.NewEncoder().Bytes([]byte(text))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encoded
+}
+
+func TestDetectCharset(t *testing.T) {
+	gbkText := encode(t, "gbk", "<p>网络爬虫，抓取网页中的链接和条目。</p>")
+	cases := []struct {
+		name        string
+		contentType string
+		body        []byte
+		want        string
+	}{
+		{"bom", "text/html; charset=gbk", []byte("\xEF\xBB\xBF<p>hi</p>"), "utf-8"},
+		{"header", "text/html; charset=GB2312", gbkText, "gbk"},
+		{"meta", "text/html", append([]byte(`<meta charset="big5">`), gbkText...), "big5"},
+		{"meta http-equiv", "text/html",
+			[]byte(`<meta http-equiv="Content-Type" content="text/html; charset=Shift_JIS">`), "shift_jis"},
+		{"sniff utf-8", "text/html", []byte("<p>网络爬虫</p>"), "utf-8"},
+		{"sniff gbk", "text/html", gbkText, "gb18030"},
+		{"sniff latin-1", "text/plain", []byte("caf\xE9 na\xEFve"), "windows-1252"},
+		{"json", "application/json", []byte(`{"a":"b"}`), "utf-8"},
+		{"binary", "image/png", []byte("\x89PNG\r\n"), ""},
+		{"no content type", "", []byte("<html><body>hi</body></html>"), "utf-8"},
+	}
+	for _, c := range cases {
+		if got := DetectCharset(c.contentType, c.body); got != c.want {
+			t.Errorf("%s: DetectCharset() = %q, want %q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDecodeCharset(t *testing.T) {
+	text := "网络爬虫"
+	decoded, err := DecodeCharset(encode(t, "gbk", text), "gbk")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(decoded) != text {
+		t.Fatalf("DecodeCharset() = %q, want %q", decoded, text)
+	}
+	decoded, err = DecodeCharset([]byte("\xEF\xBB\xBF"+text), "utf-8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decoded, []byte(text)) {
+		t.Fatalf("The BOM is not removed: %q", decoded)
+	}
+	if _, err := DecodeCharset([]byte(text), "no-such-charset"); err == nil {
+		t.Fatalf("DecodeCharset() with an unknown charset should fail")
+	}
+}
@@ -8,14 +8,31 @@
 package base
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"time"
 )
 
+// 响应体默认的最大缓冲长度，超出的部分被丢弃。
+const DefaultMaxBodySize = 10 * 1024 * 1024
+
 type Request struct {
-	httpReq *http.Request
-	depth   uint32
+	httpReq      *http.Request
+	depth        uint32
+	retries      uint32
+	priority     int
+	canonicalUrl string
+	parentUrl    string
+	tag          string
 }
 
+// HTTP请求的上下文中保存请求标签的键。
+type requestTagKey struct{}
+
 func NewRequest(httpReq *http.Request, depth uint32) *Request {
 	return &Request{httpReq: httpReq, depth: depth}
 }
@@ -24,23 +41,202 @@ func (req *Request) HttpReq() *http.Request {
 	return req.httpReq
 }
 
+// 返回HTTP请求使用给定上下文的副本。
+func (req *Request) WithContext(ctx context.Context) *Request {
+	newReq := *req
+	newReq.httpReq = withRequestTag(req.httpReq.WithContext(ctx), req.tag)
+	return &newReq
+}
+
+// 替换HTTP请求，请求的标签会被保留。
+// HTTP请求（包括其头）与请求缓存中的请求共享，需要修改时应替换为它的副本。
+func (req *Request) SetHttpReq(httpReq *http.Request) {
+	req.httpReq = withRequestTag(httpReq, req.tag)
+}
+
 func (req *Request) Depth() uint32 {
 	return req.depth
 }
 
+// 已重试的次数。
+func (req *Request) Retries() uint32 {
+	return req.retries
+}
+
+func (req *Request) SetRetries(retries uint32) {
+	req.retries = retries
+}
+
+// 优先级，值越大越先被抓取（在使用优先级顺序时有效）。
+func (req *Request) Priority() int {
+	return req.priority
+}
+
+func (req *Request) SetPriority(priority int) {
+	req.priority = priority
+}
+
+// 规范化之后的URL，用于去重。未设置时返回原始URL。
+func (req *Request) CanonicalUrl() string {
+	if req.canonicalUrl == "" && req.httpReq != nil && req.httpReq.URL != nil {
+		return req.httpReq.URL.String()
+	}
+	return req.canonicalUrl
+}
+
+func (req *Request) SetCanonicalUrl(canonicalUrl string) {
+	req.canonicalUrl = canonicalUrl
+}
+
+// 发现该请求的页面的URL，种子请求为空。
+func (req *Request) ParentUrl() string {
+	return req.parentUrl
+}
+
+func (req *Request) SetParentUrl(parentUrl string) {
+	req.parentUrl = parentUrl
+}
+
+// 请求的标签，用于在分析时选择解析函数。
+func (req *Request) Tag() string {
+	return req.tag
+}
+
+// 设置请求的标签，它同时被保存在HTTP请求的上下文中，可以通过 RequestTag 获得。
+func (req *Request) SetTag(tag string) {
+	req.tag = tag
+	req.httpReq = withRequestTag(req.httpReq, tag)
+}
+
+func withRequestTag(httpReq *http.Request, tag string) *http.Request {
+	if httpReq == nil || tag == RequestTag(httpReq) {
+		return httpReq
+	}
+	return httpReq.WithContext(context.WithValue(httpReq.Context(), requestTagKey{}, tag))
+}
+
+// 获取HTTP请求的标签，重定向之后的请求的标签与原来的请求相同。
+func RequestTag(httpReq *http.Request) string {
+	if httpReq == nil {
+		return ""
+	}
+	tag, _ := httpReq.Context().Value(requestTagKey{}).(string)
+	return tag
+}
+
 func (req *Request) Valid() bool {
 	return req.httpReq != nil && req.httpReq.URL != nil
 }
 
 type Response struct {
-	httpResp *http.Response
-	depth    uint32
+	httpResp  *http.Response
+	depth     uint32
+	unchanged bool
+	buffered  bool
+	body      []byte
+	truncated bool
+	charset   string
+	text      []byte
+	latency   time.Duration
 }
 
 func NewResponse(httpResp *http.Response, depth uint32) *Response {
 	return &Response{httpResp: httpResp, depth: depth}
 }
 
+// 创建响应并缓冲其响应体，参见 Buffer。
+func NewBufferedResponse(httpResp *http.Response, depth uint32, maxBodySize int64) (*Response, error) {
+	resp := NewResponse(httpResp, depth)
+	if err := resp.Buffer(maxBodySize); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// 读取最多maxBodySize（不大于0时为DefaultMaxBodySize）字节的响应体并关闭原来的响应体。
+// 文本内容会被检测字符集并转换为UTF-8，之后HTTP响应的响应体被替换为转换后内容的读取器，
+// Content-Type头中的字符集改为utf-8，原来的字符集记录在 CharsetHeader 头中。
+func (resp *Response) Buffer(maxBodySize int64) error {
+	if resp.buffered || resp.httpResp == nil || resp.httpResp.Body == nil {
+		return nil
+	}
+	if maxBodySize <= 0 {
+		maxBodySize = DefaultMaxBodySize
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.httpResp.Body, maxBodySize+1))
+	resp.httpResp.Body.Close()
+	if err != nil {
+		return err
+	}
+	if int64(len(body)) > maxBodySize {
+		body = body[:maxBodySize]
+		resp.truncated = true
+	}
+	resp.body = body
+	resp.text = body
+	resp.buffered = true
+	contentType := resp.httpResp.Header.Get("Content-Type")
+	if name := DetectCharset(contentType, body); name != "" {
+		if text, err := DecodeCharset(body, name); err == nil {
+			resp.charset = name
+			resp.text = text
+			resp.setUtf8ContentType(contentType)
+		}
+	}
+	resp.httpResp.Body = resp.TextReader()
+	return nil
+}
+
+func (resp *Response) setUtf8ContentType(contentType string) {
+	header := resp.httpResp.Header
+	if header == nil {
+		header = make(http.Header)
+		resp.httpResp.Header = header
+	}
+	header.Set(CharsetHeader, resp.charset)
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return
+	}
+	params["charset"] = "utf-8"
+	header.Set("Content-Type", mime.FormatMediaType(mediaType, params))
+}
+
+// 响应体是否已被缓冲。
+func (resp *Response) Buffered() bool {
+	return resp.buffered
+}
+
+// 缓冲的响应体，不应被修改。
+func (resp *Response) Body() []byte {
+	return resp.body
+}
+
+// 返回缓冲的原始响应体的一个新的读取器。
+func (resp *Response) BodyReader() io.ReadCloser {
+	return ioutil.NopCloser(bytes.NewReader(resp.body))
+}
+
+// 转换为UTF-8的响应体，不是文本时与 Body 相同。
+func (resp *Response) Text() string {
+	return string(resp.text)
+}
+
+// 返回转换为UTF-8的响应体的一个新的读取器。
+func (resp *Response) TextReader() io.ReadCloser {
+	return ioutil.NopCloser(bytes.NewReader(resp.text))
+}
+
+// 检测到的响应体原来的字符集，不是文本时为空。
+func (resp *Response) Charset() string {
+	return resp.charset
+}
+
+// 响应体是否因超过最大长度而被截断。
+func (resp *Response) Truncated() bool {
+	return resp.truncated
+}
+
 func (resp *Response) HttpResp() *http.Response {
 	return resp.httpResp
 }
@@ -49,6 +245,24 @@ func (resp *Response) Depth() uint32 {
 	return resp.depth
 }
 
+// 内容是否与缓存中的相同（条件请求返回了304）。
+func (resp *Response) Unchanged() bool {
+	return resp.unchanged
+}
+
+func (resp *Response) SetUnchanged(unchanged bool) {
+	resp.unchanged = unchanged
+}
+
+// 从发出请求到收到响应头的时间，没有经过网络（如来自缓存）时为0。
+func (resp *Response) Latency() time.Duration {
+	return resp.latency
+}
+
+func (resp *Response) SetLatency(latency time.Duration) {
+	resp.latency = latency
+}
+
 func (resp *Response) Valid() bool {
 	return resp.httpResp != nil && resp.httpResp.Body != nil
 }
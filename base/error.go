@@ -18,6 +18,7 @@ const (
 	DOWNLOADER_ERROR      ErrorType = "Dowloader Error"
 	ANALYZER_ERROR        ErrorType = "Analyzer Error"
 	ITEM_PROCCESSOR_ERROR ErrorType = "Item Proccessor Error"
+	ROBOTS_ERROR          ErrorType = "Robots Disallowed Error"
 )
 
 type CrawlerError interface {
@@ -0,0 +1,177 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 16:48:05
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 16:48:05
+ */
+
+package dedup
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// 每个新过滤器的误判率相对于上一个的比例。
+const bloomTighteningRatio = 0.5
+
+type bloomFilter struct {
+	bits     []uint64
+	m        uint64 // 位数。
+	k        uint32 // 哈希函数个数。
+	capacity uint64
+	count    uint64
+}
+
+func newBloomFilter(capacity uint64, fpRate float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint32(math.Ceil(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomFilter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+// 使用双重哈希得到第i个位置。
+func (bf *bloomFilter) location(h1 uint64, h2 uint64, i uint32) uint64 {
+	return (h1 + uint64(i)*h2) % bf.m
+}
+
+func (bf *bloomFilter) test(h1 uint64, h2 uint64) bool {
+	for i := uint32(0); i < bf.k; i++ {
+		loc := bf.location(h1, h2, i)
+		if bf.bits[loc/64]&(1<<(loc%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (bf *bloomFilter) add(h1 uint64, h2 uint64) {
+	for i := uint32(0); i < bf.k; i++ {
+		loc := bf.location(h1, h2, i)
+		bf.bits[loc/64] |= 1 << (loc % 64)
+	}
+	bf.count++
+}
+
+// 可扩展的布隆过滤器。
+// 当前过滤器达到容量后会追加一个容量加倍、误判率减半的过滤器，
+// 使总体误判率始终不超过给定的值。
+type bloomSeenSet struct {
+	filters         []*bloomFilter
+	initialCapacity uint64
+	fpRate          float64
+	count           uint64
+	mutex           sync.RWMutex
+}
+
+func NewBloomSeenSet(initialCapacity uint64, fpRate float64) (SeenSet, error) {
+	if initialCapacity == 0 {
+		return nil, errors.New("The initial capacity of bloom filter can not be 0!\n")
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		errMsg := fmt.Sprintf("The false positive rate of bloom filter must be in (0, 1)! (fpRate=%f)\n", fpRate)
+		return nil, errors.New(errMsg)
+	}
+	set := &bloomSeenSet{
+		filters:         make([]*bloomFilter, 0),
+		initialCapacity: initialCapacity,
+		fpRate:          fpRate,
+	}
+	set.grow()
+	return set, nil
+}
+
+func (set *bloomSeenSet) grow() {
+	n := len(set.filters)
+	capacity := set.initialCapacity << uint(n)
+	fpRate := set.fpRate * (1 - bloomTighteningRatio) * math.Pow(bloomTighteningRatio, float64(n))
+	set.filters = append(set.filters, newBloomFilter(capacity, fpRate))
+}
+
+func hashes(url string) (uint64, uint64) {
+	h1 := fingerprint(url)
+	h := fnv.New64()
+	h.Write([]byte(url))
+	h2 := h.Sum64() | 1
+	return h1, h2
+}
+
+func (set *bloomSeenSet) contains(h1 uint64, h2 uint64) bool {
+	for _, bf := range set.filters {
+		if bf.test(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+func (set *bloomSeenSet) Add(url string) (bool, error) {
+	h1, h2 := hashes(url)
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if set.contains(h1, h2) {
+		return false, nil
+	}
+	current := set.filters[len(set.filters)-1]
+	if current.count >= current.capacity {
+		set.grow()
+		current = set.filters[len(set.filters)-1]
+	}
+	current.add(h1, h2)
+	set.count++
+	return true, nil
+}
+
+func (set *bloomSeenSet) Contains(url string) (bool, error) {
+	h1, h2 := hashes(url)
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	return set.contains(h1, h2), nil
+}
+
+func (set *bloomSeenSet) Count() uint64 {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	return set.count
+}
+
+// 根据当前的填充量估算总体误判率。
+func (set *bloomSeenSet) estimatedFpRate() float64 {
+	miss := 1.0
+	for _, bf := range set.filters {
+		p := math.Pow(1-math.Exp(-float64(bf.k)*float64(bf.count)/float64(bf.m)), float64(bf.k))
+		miss *= 1 - p
+	}
+	return 1 - miss
+}
+
+var bloomSummaryTemplate = "type: bloom, count: %d, filters: %d, bits: %d, " +
+	"targetFpRate: %g, estimatedFpRate: %g"
+
+func (set *bloomSeenSet) Summary() string {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	var bits uint64
+	for _, bf := range set.filters {
+		bits += bf.m
+	}
+	return fmt.Sprintf(bloomSummaryTemplate, set.count, len(set.filters), bits,
+		set.fpRate, set.estimatedFpRate())
+}
+
+func (set *bloomSeenSet) Close() error {
+	return nil
+}
@@ -0,0 +1,249 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 17:15:42
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 17:15:42
+ */
+
+package dedup
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+const (
+	fileSeenSetMagic      = "WCSEEN01"
+	fileSeenSetHeaderSize = 24
+	fileSeenSetMinSlots   = 1 << 16
+)
+
+// 基于磁盘文件的实现。
+// 文件是一个以URL指纹为元素的开放寻址哈希表，
+// 内存占用与URL数量无关，并且在重启之后仍然有效。
+type fileSeenSet struct {
+	path     string
+	file     *os.File
+	capacity uint64 // 槽位数，总是2的幂。
+	count    uint64
+	mutex    sync.RWMutex
+}
+
+func NewFileSeenSet(path string) (SeenSet, error) {
+	set := &fileSeenSet{path: path}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	set.file = file
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		err = set.init(file, fileSeenSetMinSlots)
+	} else {
+		err = set.readHeader()
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return set, nil
+}
+
+func (set *fileSeenSet) init(file *os.File, capacity uint64) error {
+	if err := file.Truncate(int64(fileSeenSetHeaderSize + capacity*8)); err != nil {
+		return err
+	}
+	header := make([]byte, fileSeenSetHeaderSize)
+	copy(header, fileSeenSetMagic)
+	binary.LittleEndian.PutUint64(header[8:], capacity)
+	if _, err := file.WriteAt(header, 0); err != nil {
+		return err
+	}
+	set.capacity = capacity
+	set.count = 0
+	return set.writeCount(file)
+}
+
+func (set *fileSeenSet) readHeader() error {
+	header := make([]byte, fileSeenSetHeaderSize)
+	if _, err := set.file.ReadAt(header, 0); err != nil {
+		return err
+	}
+	if string(header[:8]) != fileSeenSetMagic {
+		errMsg := fmt.Sprintf("The file '%s' is not a seen set file!\n", set.path)
+		return errors.New(errMsg)
+	}
+	set.capacity = binary.LittleEndian.Uint64(header[8:])
+	set.count = binary.LittleEndian.Uint64(header[16:])
+	if set.capacity == 0 || set.capacity&(set.capacity-1) != 0 {
+		errMsg := fmt.Sprintf("The seen set file '%s' is broken! (capacity=%d)\n", set.path, set.capacity)
+		return errors.New(errMsg)
+	}
+	return nil
+}
+
+func (set *fileSeenSet) writeCount(file *os.File) error {
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, set.count)
+	_, err := file.WriteAt(buf, 16)
+	return err
+}
+
+func slotOffset(slot uint64) int64 {
+	return int64(fileSeenSetHeaderSize + slot*8)
+}
+
+// 查找指纹所在的槽位，不存在时返回第一个空槽位。
+func (set *fileSeenSet) find(file *os.File, capacity uint64, fp uint64) (uint64, bool, error) {
+	buf := make([]byte, 8)
+	mask := capacity - 1
+	for slot, i := fp&mask, uint64(0); i < capacity; slot, i = (slot+1)&mask, i+1 {
+		if _, err := file.ReadAt(buf, slotOffset(slot)); err != nil {
+			return 0, false, err
+		}
+		v := binary.LittleEndian.Uint64(buf)
+		if v == 0 {
+			return slot, false, nil
+		}
+		if v == fp {
+			return slot, true, nil
+		}
+	}
+	return 0, false, errors.New("The seen set file is full!\n")
+}
+
+func (set *fileSeenSet) insert(file *os.File, capacity uint64, fp uint64) (bool, error) {
+	slot, found, err := set.find(file, capacity, fp)
+	if err != nil || found {
+		return false, err
+	}
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, fp)
+	if _, err := file.WriteAt(buf, slotOffset(slot)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// 指纹0用于表示空槽位。
+func fileFingerprint(url string) uint64 {
+	fp := fingerprint(url)
+	if fp == 0 {
+		fp = 1
+	}
+	return fp
+}
+
+// 将哈希表扩大一倍。
+func (set *fileSeenSet) grow() error {
+	tmpPath := set.path + ".tmp"
+	tmpFile, err := os.OpenFile(tmpPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	newSet := &fileSeenSet{path: tmpPath, file: tmpFile}
+	if err := newSet.init(tmpFile, set.capacity*2); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	reader := bufio.NewReader(io.NewSectionReader(set.file, fileSeenSetHeaderSize, int64(set.capacity*8)))
+	buf := make([]byte, 8)
+	for i := uint64(0); i < set.capacity; i++ {
+		if _, err := io.ReadFull(reader, buf); err != nil {
+			tmpFile.Close()
+			return err
+		}
+		fp := binary.LittleEndian.Uint64(buf)
+		if fp == 0 {
+			continue
+		}
+		if _, err := newSet.insert(tmpFile, newSet.capacity, fp); err != nil {
+			tmpFile.Close()
+			return err
+		}
+		newSet.count++
+	}
+	if err := newSet.writeCount(tmpFile); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	if err := os.Rename(tmpPath, set.path); err != nil {
+		tmpFile.Close()
+		return err
+	}
+	set.file.Close()
+	set.file = tmpFile
+	set.capacity = newSet.capacity
+	set.count = newSet.count
+	return nil
+}
+
+func (set *fileSeenSet) Add(url string) (bool, error) {
+	fp := fileFingerprint(url)
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if (set.count+1)*2 > set.capacity {
+		if err := set.grow(); err != nil {
+			errMsg := fmt.Sprintf("Grow seen set file '%s' error: %s\n", set.path, err)
+			return false, errors.New(errMsg)
+		}
+	}
+	added, err := set.insert(set.file, set.capacity, fp)
+	if err != nil {
+		errMsg := fmt.Sprintf("Write seen set file '%s' error: %s\n", set.path, err)
+		return false, errors.New(errMsg)
+	}
+	if added {
+		set.count++
+		if err := set.writeCount(set.file); err != nil {
+			errMsg := fmt.Sprintf("Write seen set file '%s' error: %s\n", set.path, err)
+			return true, errors.New(errMsg)
+		}
+	}
+	return added, nil
+}
+
+func (set *fileSeenSet) Contains(url string) (bool, error) {
+	fp := fileFingerprint(url)
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	_, found, err := set.find(set.file, set.capacity, fp)
+	if err != nil {
+		errMsg := fmt.Sprintf("Read seen set file '%s' error: %s\n", set.path, err)
+		return false, errors.New(errMsg)
+	}
+	return found, nil
+}
+
+func (set *fileSeenSet) Count() uint64 {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	return set.count
+}
+
+var fileSummaryTemplate = "type: file, count: %d, slots: %d, path: %s"
+
+func (set *fileSeenSet) Summary() string {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	return fmt.Sprintf(fileSummaryTemplate, set.count, set.capacity, set.path)
+}
+
+func (set *fileSeenSet) Close() error {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if err := set.file.Sync(); err != nil {
+		set.file.Close()
+		return err
+	}
+	return set.file.Close()
+}
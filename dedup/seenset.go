@@ -0,0 +1,73 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 16:31:27
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 16:31:27
+ */
+
+package dedup
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// 已见过的URL的集合，用于请求去重。
+// 实现需要是并发安全的。
+type SeenSet interface {
+	// 若url未见过则将其记录并返回true，否则返回false。
+	// 只有基于磁盘等外部存储的实现会返回错误。
+	Add(url string) (bool, error)
+	Contains(url string) (bool, error)
+	Count() uint64
+	Summary() string
+	Close() error
+}
+
+// 计算URL的64位指纹。
+func fingerprint(url string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(url))
+	return h.Sum64()
+}
+
+type mapSeenSet struct {
+	urls  map[string]bool
+	mutex sync.RWMutex
+}
+
+// 基于map的实现，保存完整的URL，适合较小规模的抓取。
+func NewMapSeenSet() SeenSet {
+	return &mapSeenSet{urls: make(map[string]bool)}
+}
+
+func (set *mapSeenSet) Add(url string) (bool, error) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	if set.urls[url] {
+		return false, nil
+	}
+	set.urls[url] = true
+	return true, nil
+}
+
+func (set *mapSeenSet) Contains(url string) (bool, error) {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	return set.urls[url], nil
+}
+
+func (set *mapSeenSet) Count() uint64 {
+	set.mutex.RLock()
+	defer set.mutex.RUnlock()
+	return uint64(len(set.urls))
+}
+
+func (set *mapSeenSet) Summary() string {
+	return fmt.Sprintf("type: map, count: %d", set.Count())
+}
+
+func (set *mapSeenSet) Close() error {
+	return nil
+}
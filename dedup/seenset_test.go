@@ -0,0 +1,131 @@
+package dedup
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testSeenSet(t *testing.T, set SeenSet, n int) {
+	for i := 0; i < n; i++ {
+		url := fmt.Sprintf("http://example.com/%d", i)
+		if added, err := set.Add(url); err != nil || !added {
+			t.Fatalf("Add(%q) = (%v, %v), want (true, nil)", url, added, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		url := fmt.Sprintf("http://example.com/%d", i)
+		if added, err := set.Add(url); err != nil || added {
+			t.Fatalf("Add(%q) again = (%v, %v), want (false, nil)", url, added, err)
+		}
+		if seen, err := set.Contains(url); err != nil || !seen {
+			t.Fatalf("Contains(%q) = (%v, %v), want (true, nil)", url, seen, err)
+		}
+	}
+	if count := set.Count(); count != uint64(n) {
+		t.Fatalf("Count() = %d, want %d", count, n)
+	}
+}
+
+func TestMapSeenSet(t *testing.T) {
+	set := NewMapSeenSet()
+	testSeenSet(t, set, 1000)
+	if seen, _ := set.Contains("http://example.com/new"); seen {
+		t.Fatalf("Contains() of a new url should be false")
+	}
+}
+
+func TestBloomSeenSet(t *testing.T) {
+	// 初始容量较小，以测试过滤器的扩展。
+	set, err := NewBloomSeenSet(1000, 0.001)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 10000
+	// 误判时Add返回false，因此只检查没有漏判，且误判的数量不多。
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		added, err := set.Add(fmt.Sprintf("http://example.com/%d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !added {
+			falsePositives++
+		}
+	}
+	for i := 0; i < n; i++ {
+		url := fmt.Sprintf("http://example.com/%d", i)
+		if seen, err := set.Contains(url); err != nil || !seen {
+			t.Fatalf("Contains(%q) = (%v, %v), want (true, nil)", url, seen, err)
+		}
+	}
+	for i := 0; i < n; i++ {
+		if seen, _ := set.Contains(fmt.Sprintf("http://other.com/%d", i)); seen {
+			falsePositives++
+		}
+	}
+	if falsePositives > n/100 {
+		t.Fatalf("Too many false positives: %d/%d", falsePositives, 2*n)
+	}
+}
+
+func TestFileSeenSet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seenset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "seen.db")
+	set, err := NewFileSeenSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 超过初始槽位数的一半，以测试哈希表的扩大。
+	n := fileSeenSetMinSlots
+	testSeenSet(t, set, n)
+	if err := set.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	set, err = NewFileSeenSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer set.Close()
+	if count := set.Count(); count != uint64(n) {
+		t.Fatalf("Count() after reopening = %d, want %d", count, n)
+	}
+	if seen, err := set.Contains("http://example.com/0"); err != nil || !seen {
+		t.Fatalf("Contains() after reopening = (%v, %v)", seen, err)
+	}
+}
+
+func TestFileSeenSetErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "seenset")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "seen.db")
+	if err := ioutil.WriteFile(path, []byte("not a seen set file at all"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileSeenSet(path); err == nil {
+		t.Fatalf("NewFileSeenSet() should fail for a broken file")
+	}
+
+	set, err := NewFileSeenSet(filepath.Join(dir, "closed.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	set.Close()
+	// 文件关闭之后的读写返回错误而不是panic。
+	if _, err := set.Add("http://example.com/"); err == nil {
+		t.Fatalf("Add() on a closed set should fail")
+	}
+	if _, err := set.Contains("http://example.com/"); err == nil {
+		t.Fatalf("Contains() on a closed set should fail")
+	}
+}
@@ -8,6 +8,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
@@ -31,12 +32,6 @@ func genHttpClient() *http.Client {
 }
 
 func main() {
-	channelArgs := base.NewChannelArgs(10, 10, 10, 10)
-	poolBaseArgs := base.NewPoolBaseArgs(3, 3)
-	crawlDepth := uint32(10)
-	httpClientGenerator := genHttpClient
-	respParsers := getResponseParsers()
-	itemProcessors := getItemProcessors()
 	startUrl := "https://www.zhihu.com/collection/20615676"
 	firstHttpReq, err := http.NewRequest("GET", startUrl, nil)
 	if err != nil {
@@ -50,7 +45,19 @@ func main() {
 	maxIdleCount := uint(1000)
 	checkCountChan := tool.Monitoring(scheduler, intervalNs, maxIdleCount, true, false, record)
 
-	scheduler.Start(channelArgs, poolBaseArgs, crawlDepth, httpClientGenerator, respParsers, itemProcessors, firstHttpReq)
+	config := sched.Config{
+		ChannelArgs:         base.NewChannelArgs(10, 10, 10, 10),
+		PoolBaseArgs:        base.NewPoolBaseArgs(3, 3),
+		CrawlDepth:          10,
+		HttpClientGenerator: genHttpClient,
+		RespParsers:         getResponseParsers(),
+		ItemProcessors:      getItemProcessors(),
+		Seeds:               []*http.Request{firstHttpReq},
+	}
+	if err := scheduler.Start(context.Background(), config); err != nil {
+		logger.Errorln(err)
+		return
+	}
 
 	<-checkCountChan
 }
@@ -70,7 +77,14 @@ func record(level byte, content string) {
 }
 
 func getResponseParsers() []analyzer.ParseResponse {
+	linkExtractor, err := analyzer.NewLinkExtractor(analyzer.LinkRules{
+		Tags: []string{"a"},
+	})
+	if err != nil {
+		panic(err)
+	}
 	parsers := []analyzer.ParseResponse{
+		linkExtractor.Parse,
 		parseForATag,
 	}
 	return parsers
@@ -105,12 +119,7 @@ func parseForATag(httpResp *http.Response, respDepth uint32) ([]base.Data, []err
 		return nil, []error{err}
 	}
 	var reqUrl *url.URL = httpResp.Request.URL
-	var httpRespBody io.ReadCloser = httpResp.Body
-	defer func() {
-		if httpRespBody != nil {
-			httpRespBody.Close()
-		}
-	}()
+	var httpRespBody io.Reader = httpResp.Body
 
 	dataList := make([]base.Data, 0)
 	errs := make([]error, 0)
@@ -120,30 +129,8 @@ func parseForATag(httpResp *http.Response, respDepth uint32) ([]base.Data, []err
 		return dataList, errs
 	}
 
+	// 链接由链接提取器生成，这里只记录链接的文本。
 	doc.Find("a").Each(func(index int, sel *goquery.Selection) {
-		href, exists := sel.Attr("href")
-		if !exists || href == "" || href == "#" || href == "/" {
-			return
-		}
-		href = strings.TrimSpace(href)
-		lowerHref := strings.ToLower(href)
-		if href != "" && !strings.HasPrefix(lowerHref, "javascript") {
-			aUrl, err := url.Parse(href)
-			if err != nil {
-				errs = append(errs, err)
-				return
-			}
-			if !aUrl.IsAbs() {
-				aUrl = reqUrl.ResolveReference(aUrl)
-			}
-			httpReq, err := http.NewRequest("GET", aUrl.String(), nil)
-			if err != nil {
-				errs = append(errs, err)
-			} else {
-				req := base.NewRequest(httpReq, respDepth)
-				dataList = append(dataList, req)
-			}
-		}
 		text := strings.TrimSpace(sel.Text())
 		if text != "" {
 			imap := make(map[string]interface{})
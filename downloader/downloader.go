@@ -8,21 +8,45 @@
 package downloader
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"logging"
 	"net/http"
+	"time"
 	"webcrawler/base"
 	mdw "webcrawler/middleware"
+	"webcrawler/warc"
 )
 
 var downloaderIdGenerator mdw.IdGenerator = mdw.NewIdGenerator()
 
+var logger logging.Logger = base.NewLogger()
+
 type PageDownloader interface {
 	Id() uint32
 	Download(req base.Request) (*base.Response, error)
 }
 
 type myPageDownloader struct {
-	id         uint32
-	httpClient http.Client
+	id          uint32
+	httpClient  http.Client
+	retryPolicy RetryPolicy
+	rateLimiter RateLimiter
+	httpCache   HttpCache
+	warcWriter  warc.Writer
+	maxBodySize int64
+}
+
+// 下载器的可选项，零值表示不启用相应的功能。
+type Options struct {
+	RetryPolicy RetryPolicy
+	RateLimiter RateLimiter
+	HttpCache   HttpCache
+	WarcWriter  warc.Writer // 由调用方负责关闭。
+	MaxBodySize int64       // 响应体的最大缓冲长度，0表示 base.DefaultMaxBodySize。
 }
 
 func genDownloaderId() uint32 {
@@ -37,15 +61,196 @@ func NewPageDownloader(client *http.Client) PageDownloader {
 	return &myPageDownloader{id: id, httpClient: *client}
 }
 
+func NewRetryPageDownloader(client *http.Client, retryPolicy RetryPolicy) PageDownloader {
+	return NewLimitedPageDownloader(client, retryPolicy, nil)
+}
+
+// retryPolicy为nil时不重试，rateLimiter为nil时不限制速率。
+func NewLimitedPageDownloader(client *http.Client, retryPolicy RetryPolicy, rateLimiter RateLimiter) PageDownloader {
+	return NewPageDownloaderWithOptions(client, Options{RetryPolicy: retryPolicy, RateLimiter: rateLimiter})
+}
+
+func NewPageDownloaderWithOptions(client *http.Client, options Options) PageDownloader {
+	id := genDownloaderId()
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &myPageDownloader{
+		id:          id,
+		httpClient:  *client,
+		retryPolicy: options.RetryPolicy,
+		rateLimiter: options.RateLimiter,
+		httpCache:   options.HttpCache,
+		warcWriter:  options.WarcWriter,
+		maxBodySize: options.MaxBodySize,
+	}
+}
+
 func (dl *myPageDownloader) Id() uint32 {
 	return dl.id
 }
 
 func (dl *myPageDownloader) Download(req base.Request) (*base.Response, error) {
 	httpReq := req.HttpReq()
+	if req.Retries() > 0 && httpReq.GetBody != nil {
+		body, err := httpReq.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		httpReq.Body = body
+	}
+	var cacheKey string
+	var cached *CachedResponse
+	if dl.httpCache != nil && cacheableRequest(httpReq) {
+		cacheKey = req.CanonicalUrl()
+		var err error
+		cached, err = dl.httpCache.Get(cacheKey)
+		if err != nil {
+			// 缓存出错不影响这次下载。
+			logger.Warnf("Failed to read the cached response (requestUrl=%s): %s\n", httpReq.URL, err)
+			cached = nil
+		}
+		if cached != nil && !cached.matches(httpReq) {
+			cached = nil
+		}
+		if cached != nil && cached.Fresh(time.Now()) && !revalidateRequest(httpReq) {
+			return dl.bufferedResponse(cached.httpResponse(httpReq, CACHE_STATUS_HIT), req.Depth())
+		}
+		if cached != nil && cached.hasValidators() {
+			httpReq = conditionalRequest(httpReq, cached)
+		} else {
+			cached = nil
+		}
+	}
+	if dl.rateLimiter != nil {
+		if err := dl.rateLimiter.WaitRequest(httpReq.Context(), httpReq.URL.Host); err != nil {
+			return nil, err
+		}
+	}
+	fetchTime := time.Now()
 	httpResp, err := dl.httpClient.Do(httpReq)
+	// 只计算网络上的时间，不包括限速的等待和响应体的读取。
+	latency := time.Since(fetchTime)
+	if err == nil && dl.rateLimiter != nil {
+		// 限制的是响应体的字节数（Transport自动解压时为解压后的字节数）。
+		httpResp.Body = &rateLimitedBody{
+			body:    httpResp.Body,
+			limiter: dl.rateLimiter,
+			ctx:     httpReq.Context(),
+			host:    httpReq.URL.Host,
+		}
+	}
+	if err == nil && dl.warcWriter != nil {
+		if err := dl.archive(req, httpReq, httpResp, fetchTime); err != nil {
+			httpResp.Body.Close()
+			return nil, err
+		}
+	}
+	if dl.shouldRetry(req, httpResp, err) {
+		delay := dl.retryPolicy.Backoff(req.Retries(), httpResp)
+		var cause string
+		var statusCode int
+		if err != nil {
+			cause = err.Error()
+		} else {
+			statusCode = httpResp.StatusCode
+			cause = fmt.Sprintf("Unexpected status code %d", statusCode)
+			io.Copy(ioutil.Discard, httpResp.Body)
+			httpResp.Body.Close()
+		}
+		return nil, newRetryError(delay, statusCode, IsTimeout(err), latency, fmt.Sprintf("%s (requestUrl=%s, retries=%d)", cause, httpReq.URL, req.Retries()))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && httpResp.StatusCode == http.StatusNotModified {
+		io.Copy(ioutil.Discard, httpResp.Body)
+		httpResp.Body.Close()
+		now := time.Now()
+		cached.refresh(httpResp.Header, now)
+		// 缓存出错不影响这次下载。
+		if err := dl.httpCache.Put(cacheKey, cached); err != nil {
+			logger.Warnf("Failed to refresh the cached response (requestUrl=%s): %s\n", httpReq.URL, err)
+		}
+		resp, err := dl.bufferedResponse(cached.httpResponse(httpReq, CACHE_STATUS_UNCHANGED), req.Depth())
+		if err != nil {
+			return nil, err
+		}
+		resp.SetUnchanged(true)
+		resp.SetLatency(latency)
+		return resp, nil
+	}
+	if cacheKey != "" && storableResponse(httpResp) {
+		var cacheErr error
+		httpResp, cacheErr = storeResponse(dl.httpCache, cacheKey, httpResp, time.Now())
+		if cacheErr != nil {
+			logger.Warnf("Failed to cache the response (requestUrl=%s): %s\n", httpReq.URL, cacheErr)
+		}
+	}
+	resp, err := dl.bufferedResponse(httpResp, req.Depth())
 	if err != nil {
 		return nil, err
 	}
-	return base.NewResponse(httpResp, req.Depth()), nil
+	resp.SetLatency(latency)
+	return resp, nil
+}
+
+// 缓冲响应体并关闭网络连接上的响应体，使每个解析函数都能完整地读取它。
+func (dl *myPageDownloader) bufferedResponse(httpResp *http.Response, depth uint32) (*base.Response, error) {
+	resp, err := base.NewBufferedResponse(httpResp, depth, dl.maxBodySize)
+	if err != nil {
+		errMsg := fmt.Sprintf("Read response body error (requestUrl=%s): %s\n", httpResp.Request.URL, err)
+		return nil, errors.New(errMsg)
+	}
+	return resp, nil
+}
+
+func (dl *myPageDownloader) shouldRetry(req base.Request, httpResp *http.Response, err error) bool {
+	if dl.retryPolicy == nil || req.Retries()+1 >= dl.retryPolicy.MaxAttempts() {
+		return false
+	}
+	httpReq := req.HttpReq()
+	if httpReq.Body != nil && httpReq.GetBody == nil {
+		return false
+	}
+	return dl.retryPolicy.Retryable(httpResp, err)
+}
+
+// 读取最多maxBodySize字节的响应体并写入WARC文件，之后响应体仍然可以被完整地读取。
+// 超出长度限制的记录被标记为截断。
+func (dl *myPageDownloader) archive(req base.Request, httpReq *http.Request, httpResp *http.Response, fetchTime time.Time) error {
+	maxBodySize := dl.maxBodySize
+	if maxBodySize <= 0 {
+		maxBodySize = base.DefaultMaxBodySize
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxBodySize+1))
+	if err != nil {
+		return err
+	}
+	httpResp.Body = &multiReadCloser{
+		Reader: io.MultiReader(bytes.NewReader(body), httpResp.Body),
+		closer: httpResp.Body,
+	}
+	truncated := int64(len(body)) > maxBodySize
+	if truncated {
+		body = body[:maxBodySize]
+	}
+	var reqBody []byte
+	if httpReq.GetBody != nil {
+		if rc, err := httpReq.GetBody(); err == nil {
+			reqBody, _ = ioutil.ReadAll(rc)
+			rc.Close()
+		}
+	}
+	return dl.warcWriter.WriteExchange(&warc.Exchange{
+		Request:      httpReq,
+		RequestBody:  reqBody,
+		Response:     httpResp,
+		ResponseBody: body,
+		Truncated:    truncated,
+		FetchTime:    fetchTime,
+		FetchElapsed: time.Since(fetchTime),
+		Depth:        req.Depth(),
+		Via:          req.ParentUrl(),
+	})
 }
@@ -0,0 +1,355 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 20:21:05
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 20:21:05
+ */
+
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// 响应头中表示缓存状态的字段，解析函数可以据此判断页面是否有变化。
+const CacheStatusHeader = "X-Webcrawler-Cache"
+
+const (
+	CACHE_STATUS_MISS      = "miss"      // 从网络获取。
+	CACHE_STATUS_HIT       = "hit"       // 缓存仍然新鲜，没有访问网络。
+	CACHE_STATUS_UNCHANGED = "unchanged" // 条件请求返回了304，内容没有变化。
+)
+
+// 可缓存的响应体的最大长度。
+const maxCachedBodySize = 10 * 1024 * 1024
+
+// 缓存的响应。
+type CachedResponse struct {
+	Url        string
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+	StoredAt   time.Time
+	// 响应的Vary中列出的请求头在原请求中的值，只有这些值相同的请求才能使用缓存。
+	VaryHeader http.Header
+}
+
+// HTTP响应的缓存，以规范化的URL为键。
+// 实现需要是并发安全的。
+type HttpCache interface {
+	// 不存在时返回nil。
+	Get(key string) (*CachedResponse, error)
+	Put(key string, cached *CachedResponse) error
+	Summary() string
+}
+
+type diskHttpCache struct {
+	dir    string
+	hits   uint64
+	misses uint64
+	stores uint64
+}
+
+// 基于磁盘的实现，每个响应保存为dir下的一个文件。
+func NewDiskHttpCache(dir string) (HttpCache, error) {
+	if dir == "" {
+		return nil, errors.New("The http cache directory is invalid!\n")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &diskHttpCache{dir: dir}, nil
+}
+
+func (cache *diskHttpCache) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(cache.dir, name[:2], name)
+}
+
+func (cache *diskHttpCache) Get(key string) (*CachedResponse, error) {
+	file, err := os.Open(cache.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			atomic.AddUint64(&cache.misses, 1)
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+	cached := &CachedResponse{}
+	if err := gob.NewDecoder(file).Decode(cached); err != nil {
+		errMsg := fmt.Sprintf("The http cache file of '%s' is broken: %s\n", key, err)
+		return nil, errors.New(errMsg)
+	}
+	// 不同的URL的哈希值相同时视为不存在。
+	if cached.Url != key {
+		atomic.AddUint64(&cache.misses, 1)
+		return nil, nil
+	}
+	atomic.AddUint64(&cache.hits, 1)
+	return cached, nil
+}
+
+func (cache *diskHttpCache) Put(key string, cached *CachedResponse) error {
+	path := cache.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	// 临时文件的名称是唯一的，同一个键被同时写入时不会冲突。
+	file, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := file.Name()
+	cached.Url = key
+	if err := gob.NewEncoder(file).Encode(cached); err != nil {
+		file.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	atomic.AddUint64(&cache.stores, 1)
+	return nil
+}
+
+var diskHttpCacheSummaryTemplate = "dir: %s, hits: %d, misses: %d, stores: %d"
+
+func (cache *diskHttpCache) Summary() string {
+	return fmt.Sprintf(diskHttpCacheSummaryTemplate, cache.dir,
+		atomic.LoadUint64(&cache.hits), atomic.LoadUint64(&cache.misses), atomic.LoadUint64(&cache.stores))
+}
+
+// 解析Cache-Control，指令名都转为小写。
+func parseCacheControl(header http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, value := range header.Values("Cache-Control") {
+		for _, part := range strings.Split(value, ",") {
+			part = strings.TrimSpace(part)
+			if part == "" {
+				continue
+			}
+			name, arg := part, ""
+			if i := strings.Index(part, "="); i >= 0 {
+				name, arg = part[:i], strings.Trim(strings.TrimSpace(part[i+1:]), `"`)
+			}
+			directives[strings.ToLower(strings.TrimSpace(name))] = arg
+		}
+	}
+	return directives
+}
+
+// 只缓存没有请求体、没有Range的GET请求。
+func cacheableRequest(httpReq *http.Request) bool {
+	if httpReq.Method != "" && httpReq.Method != http.MethodGet {
+		return false
+	}
+	if httpReq.Header.Get("Range") != "" {
+		return false
+	}
+	_, noStore := parseCacheControl(httpReq.Header)["no-store"]
+	return !noStore
+}
+
+// 请求是否要求重新验证缓存，即带有 Cache-Control: no-cache、max-age=0 或 Pragma: no-cache。
+func revalidateRequest(httpReq *http.Request) bool {
+	directives := parseCacheControl(httpReq.Header)
+	if _, ok := directives["no-cache"]; ok {
+		return true
+	}
+	if maxAge, ok := directives["max-age"]; ok && maxAge == "0" {
+		return true
+	}
+	return strings.Contains(strings.ToLower(httpReq.Header.Get("Pragma")), "no-cache")
+}
+
+func storableResponse(httpResp *http.Response) bool {
+	if httpResp.StatusCode != http.StatusOK {
+		return false
+	}
+	if _, ok := parseCacheControl(httpResp.Header)["no-store"]; ok {
+		return false
+	}
+	for _, name := range varyNames(httpResp.Header) {
+		if name == "*" {
+			return false
+		}
+	}
+	return true
+}
+
+// 响应的Vary中列出的请求头名称。
+func varyNames(header http.Header) []string {
+	names := make([]string, 0)
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+	return names
+}
+
+// 取出请求中被响应的Vary列出的请求头。
+func varyHeader(httpReq *http.Request, respHeader http.Header) http.Header {
+	header := make(http.Header)
+	if httpReq == nil {
+		return header
+	}
+	for _, name := range varyNames(respHeader) {
+		if values := httpReq.Header.Values(name); len(values) > 0 {
+			header[name] = values
+		}
+	}
+	return header
+}
+
+// 缓存的响应是否可以用于httpReq，即Vary中列出的请求头的值都相同。
+func (cached *CachedResponse) matches(httpReq *http.Request) bool {
+	for _, name := range varyNames(cached.Header) {
+		if name == "*" {
+			return false
+		}
+		if strings.Join(httpReq.Header.Values(name), ", ") != strings.Join(cached.VaryHeader.Values(name), ", ") {
+			return false
+		}
+	}
+	return true
+}
+
+// 缓存的响应是否仍然新鲜。
+// 新鲜期依次取自max-age和Expires，没有时视为需要重新验证。
+func (cached *CachedResponse) Fresh(now time.Time) bool {
+	directives := parseCacheControl(cached.Header)
+	if _, ok := directives["no-cache"]; ok {
+		return false
+	}
+	var lifetime time.Duration
+	if maxAge, ok := directives["max-age"]; ok {
+		seconds, err := strconv.ParseInt(maxAge, 10, 64)
+		if err != nil {
+			return false
+		}
+		lifetime = time.Duration(seconds) * time.Second
+	} else if expires := cached.Header.Get("Expires"); expires != "" {
+		expiresAt, err := http.ParseTime(expires)
+		if err != nil {
+			return false
+		}
+		date, err := http.ParseTime(cached.Header.Get("Date"))
+		if err != nil {
+			date = cached.StoredAt
+		}
+		lifetime = expiresAt.Sub(date)
+	} else {
+		return false
+	}
+	age := now.Sub(cached.StoredAt)
+	if seconds, err := strconv.ParseInt(cached.Header.Get("Age"), 10, 64); err == nil && seconds > 0 {
+		age += time.Duration(seconds) * time.Second
+	}
+	return age < lifetime
+}
+
+// 是否带有可用于条件请求的验证器。
+func (cached *CachedResponse) hasValidators() bool {
+	return cached.Header.Get("ETag") != "" || cached.Header.Get("Last-Modified") != ""
+}
+
+// 生成带有If-None-Match和If-Modified-Since的条件请求。
+func conditionalRequest(httpReq *http.Request, cached *CachedResponse) *http.Request {
+	condReq := httpReq.Clone(httpReq.Context())
+	if etag := cached.Header.Get("ETag"); etag != "" {
+		condReq.Header.Set("If-None-Match", etag)
+	}
+	if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+		condReq.Header.Set("If-Modified-Since", lastModified)
+	}
+	return condReq
+}
+
+// 用304响应中的头更新缓存的响应。
+func (cached *CachedResponse) refresh(header http.Header, now time.Time) {
+	for name, values := range header {
+		switch http.CanonicalHeaderKey(name) {
+		case "Content-Length", "Content-Encoding", "Transfer-Encoding", "Content-Type":
+			continue
+		}
+		cached.Header[name] = values
+	}
+	cached.Header.Del("Age")
+	cached.StoredAt = now
+}
+
+// 由缓存的响应生成HTTP响应。
+func (cached *CachedResponse) httpResponse(httpReq *http.Request, status string) *http.Response {
+	header := cached.Header.Clone()
+	header.Set(CacheStatusHeader, status)
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cached.StatusCode, http.StatusText(cached.StatusCode)),
+		StatusCode:    cached.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       httpReq,
+	}
+}
+
+type multiReadCloser struct {
+	io.Reader
+	closer io.Closer
+}
+
+func (mrc *multiReadCloser) Close() error {
+	return mrc.closer.Close()
+}
+
+// 读取响应体并存入缓存，返回可以再次读取响应体的响应。
+// 响应体过大或读取出错时不缓存，读取和存入缓存的错误都会被返回，此时返回的响应仍然可用。
+func storeResponse(cache HttpCache, key string, httpResp *http.Response, now time.Time) (*http.Response, error) {
+	body, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxCachedBodySize+1))
+	if err != nil || len(body) > maxCachedBodySize {
+		httpResp.Body = &multiReadCloser{
+			Reader: io.MultiReader(bytes.NewReader(body), httpResp.Body),
+			closer: httpResp.Body,
+		}
+		return httpResp, err
+	}
+	httpResp.Body.Close()
+	httpResp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	httpResp.Header.Set(CacheStatusHeader, CACHE_STATUS_MISS)
+	cached := &CachedResponse{
+		StatusCode: httpResp.StatusCode,
+		Header:     httpResp.Header.Clone(),
+		Body:       body,
+		StoredAt:   now,
+		VaryHeader: varyHeader(httpResp.Request, httpResp.Header),
+	}
+	cached.Header.Del(CacheStatusHeader)
+	return httpResp, cache.Put(key, cached)
+}
@@ -0,0 +1,158 @@
+package downloader
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+	"webcrawler/base"
+)
+
+// 用于测试的缓存下载器，返回下载器和服务端收到的请求数。
+func newCachingDownloader(t *testing.T, handler http.HandlerFunc) (PageDownloader, *httptest.Server, *int32, func()) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		handler(w, r)
+	}))
+	dir, err := ioutil.TempDir("", "httpcache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := NewDiskHttpCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloader := NewPageDownloaderWithOptions(server.Client(), Options{HttpCache: cache})
+	return downloader, server, &requests, func() {
+		server.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func cacheDownload(t *testing.T, downloader PageDownloader, rawUrl string, header http.Header) *base.Response {
+	httpReq, _ := http.NewRequest("GET", rawUrl, nil)
+	for name, values := range header {
+		httpReq.Header[name] = values
+	}
+	req := base.NewRequest(httpReq, 0)
+	req.SetCanonicalUrl(rawUrl)
+	resp, err := downloader.Download(*req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func cacheStatus(resp *base.Response) string {
+	return resp.HttpResp().Header.Get(CacheStatusHeader)
+}
+
+func TestHttpCacheVary(t *testing.T) {
+	downloader, server, requests, cleanup := newCachingDownloader(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte("lang=" + r.Header.Get("Accept-Language")))
+	})
+	defer cleanup()
+	en := http.Header{"Accept-Language": {"en"}}
+	de := http.Header{"Accept-Language": {"de"}}
+	cacheDownload(t, downloader, server.URL, en)
+	if resp := cacheDownload(t, downloader, server.URL, en); cacheStatus(resp) != CACHE_STATUS_HIT {
+		t.Fatalf("The same Accept-Language should hit the cache, got %q", cacheStatus(resp))
+	}
+	if resp := cacheDownload(t, downloader, server.URL, de); resp.Text() != "lang=de" {
+		t.Fatalf("A different Accept-Language got %q", resp.Text())
+	}
+	if n := atomic.LoadInt32(requests); n != 2 {
+		t.Fatalf("requests = %d, want 2", n)
+	}
+}
+
+func TestHttpCacheVaryStar(t *testing.T) {
+	downloader, server, requests, cleanup := newCachingDownloader(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("Vary", "Accept, *")
+		w.Write([]byte("body"))
+	})
+	defer cleanup()
+	cacheDownload(t, downloader, server.URL, nil)
+	cacheDownload(t, downloader, server.URL, nil)
+	if n := atomic.LoadInt32(requests); n != 2 {
+		t.Fatalf("requests = %d, responses with Vary: * should not be cached", n)
+	}
+}
+
+func TestHttpCacheRequestNoCache(t *testing.T) {
+	downloader, server, requests, cleanup := newCachingDownloader(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cache-Control", "max-age=3600")
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte("body"))
+	})
+	defer cleanup()
+	cacheDownload(t, downloader, server.URL, nil)
+	for _, header := range []http.Header{
+		{"Cache-Control": {"no-cache"}},
+		{"Cache-Control": {"max-age=0"}},
+		{"Pragma": {"no-cache"}},
+	} {
+		resp := cacheDownload(t, downloader, server.URL, header)
+		if cacheStatus(resp) != CACHE_STATUS_UNCHANGED || resp.Text() != "body" {
+			t.Fatalf("%v: status=%q, body=%q, want a revalidated response", header, cacheStatus(resp), resp.Text())
+		}
+	}
+	if n := atomic.LoadInt32(requests); n != 4 {
+		t.Fatalf("requests = %d, want 4", n)
+	}
+}
+
+type failingHttpCache struct{}
+
+func (cache failingHttpCache) Get(key string) (*CachedResponse, error) {
+	return nil, errors.New("broken cache file")
+}
+
+func (cache failingHttpCache) Put(key string, cached *CachedResponse) error {
+	return errors.New("disk full")
+}
+
+func (cache failingHttpCache) Summary() string {
+	return ""
+}
+
+func TestHttpCacheErrorsKeepResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("body"))
+	}))
+	defer server.Close()
+	downloader := NewPageDownloaderWithOptions(server.Client(), Options{HttpCache: failingHttpCache{}})
+	if resp := cacheDownload(t, downloader, server.URL, nil); resp.Text() != "body" {
+		t.Fatalf("body = %q, the response should survive cache errors", resp.Text())
+	}
+}
+
+type errorReader struct{}
+
+func (r errorReader) Read(p []byte) (int, error) {
+	return 0, io.ErrUnexpectedEOF
+}
+
+func TestStoreResponseReadError(t *testing.T) {
+	httpResp := &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(errorReader{}),
+	}
+	if _, err := storeResponse(failingHttpCache{}, "key", httpResp, time.Now()); err != io.ErrUnexpectedEOF {
+		t.Fatalf("storeResponse() error = %v, want the read error", err)
+	}
+}
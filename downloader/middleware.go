@@ -0,0 +1,131 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 22:05:12
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 22:05:12
+ */
+
+package downloader
+
+import (
+	"errors"
+	"net/http"
+	"webcrawler/base"
+)
+
+// 下载器中间件。
+// 请求按注册的顺序经过各个中间件，响应和错误按相反的顺序经过。
+type Middleware interface {
+	// 在下载之前处理请求，可以修改请求。
+	// HTTP请求与请求缓存中的请求共享，修改时需要用 base.Request.SetHttpReq 替换为它的副本。
+	// 返回非nil的响应或错误时不再下载，也不再调用之后的中间件。
+	ProcessRequest(req *base.Request) (*base.Response, error)
+	// 处理得到的响应，可以替换它，返回 (nil, nil) 表示有意丢弃该请求。
+	// 返回 RetryError 可以让调度器稍后重试该请求。
+	ProcessResponse(req *base.Request, resp *base.Response) (*base.Response, error)
+	// 处理下载的错误，返回非nil的响应表示从错误中恢复。
+	// 返回 (nil, nil) 表示有意忽略该错误并丢弃该请求，与 ProcessResponse 返回nil相同，
+	// 调度器会把请求标记为完成而不报告错误。
+	ProcessError(req *base.Request, err error) (*base.Response, error)
+}
+
+// 由函数组成的中间件，为nil的函数表示不做处理。
+type MiddlewareFuncs struct {
+	Request  func(req *base.Request) (*base.Response, error)
+	Response func(req *base.Request, resp *base.Response) (*base.Response, error)
+	Error    func(req *base.Request, err error) (*base.Response, error)
+}
+
+func (mf MiddlewareFuncs) ProcessRequest(req *base.Request) (*base.Response, error) {
+	if mf.Request == nil {
+		return nil, nil
+	}
+	return mf.Request(req)
+}
+
+func (mf MiddlewareFuncs) ProcessResponse(req *base.Request, resp *base.Response) (*base.Response, error) {
+	if mf.Response == nil {
+		return resp, nil
+	}
+	return mf.Response(req, resp)
+}
+
+func (mf MiddlewareFuncs) ProcessError(req *base.Request, err error) (*base.Response, error) {
+	if mf.Error == nil {
+		return nil, err
+	}
+	return mf.Error(req, err)
+}
+
+// 为请求添加其中没有的头，头被添加到HTTP请求的副本中。
+func NewHeaderMiddleware(header http.Header) Middleware {
+	return MiddlewareFuncs{
+		Request: func(req *base.Request) (*base.Response, error) {
+			httpReq := req.HttpReq()
+			var newReq *http.Request
+			for name, values := range header {
+				if httpReq.Header.Get(name) != "" {
+					continue
+				}
+				if newReq == nil {
+					newReq = httpReq.Clone(httpReq.Context())
+					if newReq.Header == nil {
+						newReq.Header = make(http.Header)
+					}
+				}
+				newReq.Header[http.CanonicalHeaderKey(name)] = append([]string(nil), values...)
+			}
+			if newReq != nil {
+				req.SetHttpReq(newReq)
+			}
+			return nil, nil
+		},
+	}
+}
+
+type middlewareDownloader struct {
+	downloader  PageDownloader
+	middlewares []Middleware
+}
+
+// 用中间件包装下载器，Id与被包装的下载器相同。
+func NewMiddlewarePageDownloader(downloader PageDownloader, middlewares ...Middleware) (PageDownloader, error) {
+	if downloader == nil {
+		return nil, errors.New("The page downloader is invalid!\n")
+	}
+	for _, middleware := range middlewares {
+		if middleware == nil {
+			return nil, errors.New("The downloader middleware is invalid!\n")
+		}
+	}
+	return &middlewareDownloader{downloader: downloader, middlewares: middlewares}, nil
+}
+
+func (md *middlewareDownloader) Id() uint32 {
+	return md.downloader.Id()
+}
+
+func (md *middlewareDownloader) Download(req base.Request) (*base.Response, error) {
+	var resp *base.Response
+	var err error
+	// 已处理过请求的中间件数，只有它们会处理响应。
+	called := 0
+	for _, middleware := range md.middlewares {
+		called++
+		resp, err = middleware.ProcessRequest(&req)
+		if resp != nil || err != nil {
+			break
+		}
+	}
+	if resp == nil && err == nil {
+		resp, err = md.downloader.Download(req)
+	}
+	for i := called - 1; i >= 0; i-- {
+		if err != nil {
+			resp, err = md.middlewares[i].ProcessError(&req, err)
+		} else if resp != nil {
+			resp, err = md.middlewares[i].ProcessResponse(&req, resp)
+		}
+	}
+	return resp, err
+}
@@ -0,0 +1,95 @@
+package downloader
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"webcrawler/base"
+)
+
+// 记录收到的请求的下载器。
+type fakeDownloader struct {
+	reqs []*http.Request
+	err  error
+}
+
+func (fd *fakeDownloader) Id() uint32 {
+	return 0
+}
+
+func (fd *fakeDownloader) Download(req base.Request) (*base.Response, error) {
+	fd.reqs = append(fd.reqs, req.HttpReq())
+	if fd.err != nil {
+		return nil, fd.err
+	}
+	httpResp := &http.Response{StatusCode: http.StatusOK, Header: make(http.Header), Request: req.HttpReq()}
+	return base.NewResponse(httpResp, req.Depth()), nil
+}
+
+func TestHeaderMiddlewareCopiesRequest(t *testing.T) {
+	fd := &fakeDownloader{}
+	header := http.Header{"user-agent": {"webcrawler"}, "Accept": {"text/html"}}
+	downloader, err := NewMiddlewarePageDownloader(fd, NewHeaderMiddleware(header))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq, _ := http.NewRequest("GET", "http://example.com/", nil)
+	httpReq.Header.Set("Accept", "*/*")
+	req := base.NewRequest(httpReq, 0)
+	req.SetTag("page")
+	if _, err := downloader.Download(*req); err != nil {
+		t.Fatal(err)
+	}
+	sent := fd.reqs[0]
+	if sent == httpReq {
+		t.Fatalf("The shared request should not be modified in place")
+	}
+	if sent.Header.Get("User-Agent") != "webcrawler" || sent.Header.Get("Accept") != "*/*" {
+		t.Fatalf("sent header = %v", sent.Header)
+	}
+	if httpReq.Header.Get("User-Agent") != "" {
+		t.Fatalf("The original header was modified: %v", httpReq.Header)
+	}
+	if base.RequestTag(sent) != "page" {
+		t.Fatalf("The request tag is lost")
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	fd := &fakeDownloader{err: errors.New("network")}
+	calls := make([]string, 0)
+	newMiddleware := func(name string, recover bool) Middleware {
+		return MiddlewareFuncs{
+			Request: func(req *base.Request) (*base.Response, error) {
+				calls = append(calls, name+".request")
+				return nil, nil
+			},
+			Error: func(req *base.Request, err error) (*base.Response, error) {
+				calls = append(calls, name+".error")
+				if recover {
+					return nil, nil
+				}
+				return nil, err
+			},
+		}
+	}
+	downloader, err := NewMiddlewarePageDownloader(fd, newMiddleware("a", true), newMiddleware("b", false))
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq, _ := http.NewRequest("GET", "http://example.com/", nil)
+	resp, err := downloader.Download(*base.NewRequest(httpReq, 0))
+	// a 有意忽略了错误，请求被丢弃。
+	if resp != nil || err != nil {
+		t.Fatalf("Download() = (%v, %v), want (nil, nil)", resp, err)
+	}
+	want := []string{"a.request", "b.request", "b.error", "a.error"}
+	if len(calls) != len(want) {
+		t.Fatalf("calls = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("calls = %v, want %v", calls, want)
+		}
+	}
+}
@@ -14,13 +14,17 @@ import (
 	mdw "webcrawler/middleware"
 )
 
+// 网页下载器的生成函数，每次调用都需要返回一个新的、类型相同的下载器。
 type GenPageDownloader func() PageDownloader
 
 type PageDownloaderPool interface {
 	Take() (PageDownloader, error)
 	Return(dl PageDownloader) error
+	Resize(total uint32) error
 	Total() uint32
 	Used() uint32
+	// 关闭池，等待中的 Take 会返回错误。
+	Close()
 }
 
 type myDownloaderPool struct {
@@ -29,7 +33,14 @@ type myDownloaderPool struct {
 }
 
 func NewPageDownloaderPool(total uint32, gen GenPageDownloader) (PageDownloaderPool, error) {
-	etype := reflect.TypeOf(gen())
+	if gen == nil {
+		return nil, errors.New("The page downloader generator is invalid!\n")
+	}
+	sample := gen()
+	if sample == nil {
+		return nil, errors.New("The page downloader generator returns nil!\n")
+	}
+	etype := reflect.TypeOf(sample)
 	genEntity := func() mdw.Entity {
 		return gen()
 	}
@@ -58,6 +69,10 @@ func (dp *myDownloaderPool) Return(dl PageDownloader) error {
 	return dp.pool.Return(dl)
 }
 
+func (dp *myDownloaderPool) Resize(total uint32) error {
+	return dp.pool.Resize(total)
+}
+
 func (dp *myDownloaderPool) Total() uint32 {
 	return dp.pool.Total()
 }
@@ -65,3 +80,7 @@ func (dp *myDownloaderPool) Total() uint32 {
 func (dp *myDownloaderPool) Used() uint32 {
 	return dp.pool.Used()
 }
+
+func (dp *myDownloaderPool) Close() {
+	dp.pool.Close()
+}
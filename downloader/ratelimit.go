@@ -0,0 +1,257 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 19:47:16
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 19:47:16
+ */
+
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 令牌桶的容量相当于多长时间内产生的令牌，越小速率越平滑。
+const tokenBucketBurst = 100 * time.Millisecond
+
+// 主机的令牌桶装满并且闲置超过这个时间后会被清除，同时也是清除的间隔。
+const hostBucketIdle = time.Minute
+
+type tokenBucket struct {
+	rate   float64 // 每秒产生的令牌数。
+	burst  float64
+	tokens float64
+	last   time.Time
+	mutex  sync.Mutex
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	burst := rate * tokenBucketBurst.Seconds()
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: burst, tokens: burst, last: time.Now()}
+}
+
+// 取出n个令牌并返回需要等待的时间，令牌不足时预支之后产生的令牌。
+func (tb *tokenBucket) reserve(n float64) time.Duration {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	now := time.Now()
+	tb.tokens += now.Sub(tb.last).Seconds() * tb.rate
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+	tb.last = now
+	tb.tokens -= n
+	if tb.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-tb.tokens / tb.rate * float64(time.Second))
+}
+
+// 归还预支但没有使用的n个令牌。
+func (tb *tokenBucket) refund(n float64) {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	tb.tokens += n
+	if tb.tokens > tb.burst {
+		tb.tokens = tb.burst
+	}
+}
+
+// 判断令牌桶是否已经装满并闲置了至少idle的时间，这时清除它和新建一个没有区别。
+func (tb *tokenBucket) idle(now time.Time, idle time.Duration) bool {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+	elapsed := now.Sub(tb.last)
+	return elapsed >= idle && tb.tokens+elapsed.Seconds()*tb.rate >= tb.burst
+}
+
+// 下载的速率限制，包括全局和每个主机的请求数和字节数。
+// 同一个实例需要在所有下载器之间共享。
+type RateLimiter interface {
+	// 等待发出一个请求的许可。
+	WaitRequest(ctx context.Context, host string) error
+	// 等待读取n个字节的许可。
+	WaitBytes(ctx context.Context, host string, n int) error
+	String() string
+}
+
+type myRateLimiter struct {
+	requestRate     float64
+	byteRate        float64
+	hostRequestRate float64
+	hostByteRate    float64
+	requests        *tokenBucket
+	bytes           *tokenBucket
+	hostRequests    map[string]*tokenBucket
+	hostBytes       map[string]*tokenBucket
+	lastSweep       time.Time
+	mutex           sync.Mutex
+	description     string
+}
+
+// 各个速率的单位分别是每秒的请求数和每秒的字节数，0表示不限制。
+func NewRateLimiter(requestRate float64, byteRate float64, hostRequestRate float64, hostByteRate float64) (RateLimiter, error) {
+	if requestRate < 0 || byteRate < 0 || hostRequestRate < 0 || hostByteRate < 0 {
+		errMsg := fmt.Sprintf("The rate can not be negative! (requestRate=%g, byteRate=%g, hostRequestRate=%g, hostByteRate=%g)\n",
+			requestRate, byteRate, hostRequestRate, hostByteRate)
+		return nil, errors.New(errMsg)
+	}
+	limiter := &myRateLimiter{
+		requestRate:     requestRate,
+		byteRate:        byteRate,
+		hostRequestRate: hostRequestRate,
+		hostByteRate:    hostByteRate,
+		hostRequests:    make(map[string]*tokenBucket),
+		hostBytes:       make(map[string]*tokenBucket),
+		lastSweep:       time.Now(),
+		description: fmt.Sprintf(rateLimiterTemplate,
+			requestRate, byteRate, hostRequestRate, hostByteRate),
+	}
+	if requestRate > 0 {
+		limiter.requests = newTokenBucket(requestRate)
+	}
+	if byteRate > 0 {
+		limiter.bytes = newTokenBucket(byteRate)
+	}
+	return limiter, nil
+}
+
+// 从主机的令牌桶中取出n个令牌，需要在持有锁的情况下调用。
+// 取令牌和清除闲置的令牌桶在同一个锁中进行，这样不会把令牌取到已经被清除的令牌桶里。
+func (limiter *myRateLimiter) reserveHost(buckets map[string]*tokenBucket, rate float64, host string, n float64) (*tokenBucket, time.Duration) {
+	now := time.Now()
+	if now.Sub(limiter.lastSweep) >= hostBucketIdle {
+		limiter.sweep(now)
+	}
+	host = strings.ToLower(host)
+	bucket, ok := buckets[host]
+	if !ok {
+		bucket = newTokenBucket(rate)
+		buckets[host] = bucket
+	}
+	return bucket, bucket.reserve(n)
+}
+
+// 清除闲置的主机令牌桶，需要在持有锁的情况下调用。
+func (limiter *myRateLimiter) sweep(now time.Time) {
+	for _, buckets := range []map[string]*tokenBucket{limiter.hostRequests, limiter.hostBytes} {
+		for host, bucket := range buckets {
+			if bucket.idle(now, hostBucketIdle) {
+				delete(buckets, host)
+			}
+		}
+	}
+	limiter.lastSweep = now
+}
+
+// 从全局和主机的令牌桶中取出n个令牌，并等待其中最长的时间。
+// 等待被取消时归还取出的令牌。
+func (limiter *myRateLimiter) wait(ctx context.Context, n float64, global *tokenBucket,
+	buckets map[string]*tokenBucket, rate float64, host string) error {
+	reserved := make([]*tokenBucket, 0, 2)
+	var delay time.Duration
+	if global != nil {
+		delay = global.reserve(n)
+		reserved = append(reserved, global)
+	}
+	if rate > 0 {
+		limiter.mutex.Lock()
+		bucket, d := limiter.reserveHost(buckets, rate, host, n)
+		limiter.mutex.Unlock()
+		if d > delay {
+			delay = d
+		}
+		reserved = append(reserved, bucket)
+	}
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		for _, bucket := range reserved {
+			bucket.refund(n)
+		}
+		return ctx.Err()
+	}
+}
+
+func (limiter *myRateLimiter) WaitRequest(ctx context.Context, host string) error {
+	return limiter.wait(ctx, 1, limiter.requests, limiter.hostRequests, limiter.hostRequestRate, host)
+}
+
+func (limiter *myRateLimiter) WaitBytes(ctx context.Context, host string, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	return limiter.wait(ctx, float64(n), limiter.bytes, limiter.hostBytes, limiter.hostByteRate, host)
+}
+
+var rateLimiterTemplate = "{ requestRate: %g/s, byteRate: %g/s, hostRequestRate: %g/s, hostByteRate: %g/s }"
+
+func (limiter *myRateLimiter) String() string {
+	return limiter.description
+}
+
+// 读取时受速率限制的响应体。
+type rateLimitedBody struct {
+	body    io.ReadCloser
+	limiter RateLimiter
+	ctx     context.Context
+	host    string
+}
+
+func (rb *rateLimitedBody) Read(p []byte) (int, error) {
+	n, err := rb.body.Read(p)
+	if n > 0 {
+		if werr := rb.limiter.WaitBytes(rb.ctx, rb.host, n); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return n, err
+}
+
+func (rb *rateLimitedBody) Close() error {
+	return rb.body.Close()
+}
+
+// 受速率限制的Transport，用于不经过下载器的请求（如robots.txt）。
+type rateLimitedTransport struct {
+	transport http.RoundTripper
+	limiter   RateLimiter
+}
+
+// transport为nil时使用 http.DefaultTransport。
+func NewRateLimitedTransport(transport http.RoundTripper, limiter RateLimiter) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &rateLimitedTransport{transport: transport, limiter: limiter}
+}
+
+func (t *rateLimitedTransport) RoundTrip(httpReq *http.Request) (*http.Response, error) {
+	ctx := httpReq.Context()
+	host := httpReq.URL.Host
+	if err := t.limiter.WaitRequest(ctx, host); err != nil {
+		return nil, err
+	}
+	httpResp, err := t.transport.RoundTrip(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	httpResp.Body = &rateLimitedBody{body: httpResp.Body, limiter: t.limiter, ctx: ctx, host: host}
+	return httpResp, nil
+}
@@ -0,0 +1,99 @@
+package downloader
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefundsOnCancel(t *testing.T) {
+	limiter, err := NewRateLimiter(0, 0, 10, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 第一个请求用掉桶里唯一的令牌。
+	if err := limiter.WaitRequest(context.Background(), "a.com"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		if err := limiter.WaitRequest(ctx, "a.com"); err == nil {
+			t.Fatalf("WaitRequest() should be cancelled")
+		}
+		cancel()
+	}
+	// 被取消的等待归还了令牌，下一个请求不需要排在它们后面。
+	start := time.Now()
+	if err := limiter.WaitRequest(context.Background(), "a.com"); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 150*time.Millisecond {
+		t.Fatalf("WaitRequest() waited %s after cancelled reservations", elapsed)
+	}
+}
+
+func TestRateLimiterSweepsIdleHosts(t *testing.T) {
+	limiter, err := NewRateLimiter(0, 0, 100, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	myLimiter := limiter.(*myRateLimiter)
+	limiter.WaitRequest(context.Background(), "a.com")
+	limiter.WaitBytes(context.Background(), "a.com", 1)
+	limiter.WaitRequest(context.Background(), "b.com")
+	now := time.Now().Add(hostBucketIdle)
+	// b.com 的令牌桶刚刚被用过，不会被清除。
+	myLimiter.hostRequests["b.com"].last = now
+	myLimiter.hostRequests["b.com"].tokens = 0
+	myLimiter.mutex.Lock()
+	myLimiter.sweep(now)
+	myLimiter.mutex.Unlock()
+	if _, ok := myLimiter.hostRequests["a.com"]; ok {
+		t.Fatalf("The idle request bucket is not swept")
+	}
+	if _, ok := myLimiter.hostBytes["a.com"]; ok {
+		t.Fatalf("The idle byte bucket is not swept")
+	}
+	if _, ok := myLimiter.hostRequests["b.com"]; !ok {
+		t.Fatalf("The busy request bucket is swept")
+	}
+}
+
+func TestRateLimiterString(t *testing.T) {
+	limiter, err := NewRateLimiter(1, 2, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "{ requestRate: 1/s, byteRate: 2/s, hostRequestRate: 3/s, hostByteRate: 4/s }"
+	if got := limiter.String(); got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestRateLimitedTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(make([]byte, 200))
+	}))
+	defer server.Close()
+	limiter, err := NewRateLimiter(0, 0, 0, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: NewRateLimitedTransport(nil, limiter)}
+	start := time.Now()
+	for i := 0; i < 2; i++ {
+		httpResp, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(httpResp.Body)
+		httpResp.Body.Close()
+	}
+	// 400个字节中超出令牌桶容量的300个字节需要等待约0.3秒。
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Fatalf("The body reads were not rate limited (elapsed=%s)", elapsed)
+	}
+}
@@ -0,0 +1,238 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 21:36:48
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 21:36:48
+ */
+
+package downloader
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// 录制和回放的模式。
+type ReplayMode uint8
+
+const (
+	REPLAY_MODE_RECORD ReplayMode = 0 // 从网络下载并保存到夹具目录。
+	REPLAY_MODE_REPLAY ReplayMode = 1 // 只从夹具目录读取，不访问网络。
+)
+
+// 保存在夹具目录中的一次请求的响应。
+type fixture struct {
+	Method     string      `json:"method"`
+	Url        string      `json:"url"`
+	StatusCode int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+	Truncated  bool        `json:"truncated,omitempty"` // 录制时响应体没有被读完，Body只是开头的部分。
+}
+
+// 请求的方法、URL和请求体共同决定夹具的文件名。
+func fixtureName(req *http.Request, body []byte) string {
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	h := sha1.New()
+	h.Write([]byte(method + " " + req.URL.String() + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil)) + ".json"
+}
+
+// 读取请求体，返回可以再次发送的请求。
+// 请求不能重新获取请求体时返回它的副本，不修改调用方的请求。
+func readRequestBody(req *http.Request) ([]byte, *http.Request, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, req, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, nil, err
+		}
+		defer rc.Close()
+		body, err := ioutil.ReadAll(rc)
+		return body, req, err
+	}
+	body, err := ioutil.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	clone := req.Clone(req.Context())
+	clone.Body = ioutil.NopCloser(bytes.NewReader(body))
+	clone.GetBody = func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(body)), nil
+	}
+	return body, clone, nil
+}
+
+func (f *fixture) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", f.StatusCode, http.StatusText(f.StatusCode)),
+		StatusCode:    f.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        f.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(f.Body)),
+		ContentLength: int64(len(f.Body)),
+		Request:       req,
+	}
+}
+
+type recordTransport struct {
+	dir       string
+	transport http.RoundTripper
+}
+
+// 通过transport下载，并把响应保存到dir中。transport为nil时使用http.DefaultTransport。
+func NewRecordTransport(dir string, transport http.RoundTripper) (http.RoundTripper, error) {
+	if dir == "" {
+		return nil, errors.New("The fixture directory is invalid!\n")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &recordTransport{dir: dir, transport: transport}, nil
+}
+
+func (rt *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, sendReq, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := rt.transport.RoundTrip(sendReq)
+	if err != nil {
+		return nil, err
+	}
+	f := &fixture{
+		Method:     req.Method,
+		Url:        req.URL.String(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+	}
+	path := filepath.Join(rt.dir, fixtureName(req, reqBody))
+	resp.Body = &recordingBody{body: resp.Body, fixture: f, path: path}
+	return resp, nil
+}
+
+// 在响应体被读取的同时记录其内容，关闭时保存夹具。
+// 响应体由下载器读取，因此其长度和带宽的限制对录制同样有效。
+type recordingBody struct {
+	body    io.ReadCloser
+	buffer  bytes.Buffer
+	eof     bool
+	closed  bool
+	fixture *fixture
+	path    string
+}
+
+func (rb *recordingBody) Read(p []byte) (int, error) {
+	n, err := rb.body.Read(p)
+	rb.buffer.Write(p[:n])
+	if err == io.EOF {
+		rb.eof = true
+	}
+	return n, err
+}
+
+func (rb *recordingBody) Close() error {
+	err := rb.body.Close()
+	if rb.closed {
+		return err
+	}
+	rb.closed = true
+	rb.fixture.Body = rb.buffer.Bytes()
+	rb.fixture.Truncated = !rb.eof
+	data, jsonErr := json.MarshalIndent(rb.fixture, "", "  ")
+	if jsonErr == nil {
+		jsonErr = ioutil.WriteFile(rb.path, data, 0644)
+	}
+	if jsonErr != nil {
+		// 读取方不一定检查Close的错误，因此同时记录日志。
+		errMsg := fmt.Sprintf("Save the fixture '%s' error: %s\n", rb.path, jsonErr)
+		logger.Warn(errMsg)
+		return errors.New(errMsg)
+	}
+	return err
+}
+
+type replayTransport struct {
+	dir string
+}
+
+// 只从dir中读取响应，没有对应的夹具时返回错误。
+func NewReplayTransport(dir string) (http.RoundTripper, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		errMsg := fmt.Sprintf("The fixture path '%s' is not a directory!\n", dir)
+		return nil, errors.New(errMsg)
+	}
+	return &replayTransport{dir: dir}, nil
+}
+
+func (rt *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, _, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	name := fixtureName(req, reqBody)
+	data, err := ioutil.ReadFile(filepath.Join(rt.dir, name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			errMsg := fmt.Sprintf("No fixture for %s %s in '%s' (fixture=%s)\n", req.Method, req.URL, rt.dir, name)
+			return nil, errors.New(errMsg)
+		}
+		return nil, err
+	}
+	f := &fixture{}
+	if err := json.Unmarshal(data, f); err != nil {
+		errMsg := fmt.Sprintf("The fixture '%s' is broken: %s\n", name, err)
+		return nil, errors.New(errMsg)
+	}
+	return f.response(req), nil
+}
+
+// 录制或回放响应的网页下载器，用于离线开发解析函数和确定性的测试。
+// client为nil时使用默认的客户端，其Transport会被替换。
+func NewReplayPageDownloader(mode ReplayMode, dir string, client *http.Client) (PageDownloader, error) {
+	var transport http.RoundTripper
+	var err error
+	if client == nil {
+		client = &http.Client{}
+	}
+	switch mode {
+	case REPLAY_MODE_RECORD:
+		transport, err = NewRecordTransport(dir, client.Transport)
+	case REPLAY_MODE_REPLAY:
+		transport, err = NewReplayTransport(dir)
+	default:
+		errMsg := fmt.Sprintf("Unsupported replay mode %d!\n", mode)
+		err = errors.New(errMsg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	replayClient := *client
+	replayClient.Transport = transport
+	return NewPageDownloader(&replayClient), nil
+}
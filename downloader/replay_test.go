@@ -0,0 +1,98 @@
+package downloader
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"webcrawler/base"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.Method + " " + r.URL.Path + " " + string(body)))
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	recorder, err := NewReplayPageDownloader(REPLAY_MODE_RECORD, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 没有GetBody的请求体，录制时不能修改调用方的请求。
+	httpReq, _ := http.NewRequest("POST", server.URL+"/post", nil)
+	originalBody := ioutil.NopCloser(strings.NewReader("data"))
+	httpReq.Body = originalBody
+	resp, err := recorder.Download(*base.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resp.Text(); text != "POST /post data" {
+		t.Fatalf("recorded body = %q", text)
+	}
+	if httpReq.Body != originalBody || httpReq.GetBody != nil {
+		t.Fatalf("The caller's request was modified")
+	}
+
+	replayer, err := NewReplayPageDownloader(REPLAY_MODE_REPLAY, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq, _ = http.NewRequest("POST", server.URL+"/post", strings.NewReader("data"))
+	resp, err = replayer.Download(*base.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := resp.Text(); text != "POST /post data" {
+		t.Fatalf("replayed body = %q", text)
+	}
+	httpReq, _ = http.NewRequest("POST", server.URL+"/post", strings.NewReader("other"))
+	if _, err := replayer.Download(*base.NewRequest(httpReq, 0)); err == nil || !strings.Contains(err.Error(), "No fixture") {
+		t.Fatalf("A request without fixture should fail clearly, got %v", err)
+	}
+}
+
+func TestRecordRespectsMaxBodySize(t *testing.T) {
+	body := bytes.Repeat([]byte("x"), 1024)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(body)
+	}))
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	transport, err := NewRecordTransport(dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	downloader := NewPageDownloaderWithOptions(&http.Client{Transport: transport}, Options{MaxBodySize: 100})
+	httpReq, _ := http.NewRequest("GET", server.URL+"/big", nil)
+	resp, err := downloader.Download(*base.NewRequest(httpReq, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Truncated() || len(resp.Body()) != 100 {
+		t.Fatalf("Truncated() = %v, len(Body()) = %d", resp.Truncated(), len(resp.Body()))
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("fixtures = %d, want 1", len(files))
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	if !strings.Contains(string(data), `"truncated": true`) {
+		t.Fatalf("The fixture should be marked as truncated: %s", data)
+	}
+}
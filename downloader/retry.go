@@ -0,0 +1,195 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 13:05:22
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 13:05:22
+ */
+
+package downloader
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+)
+
+var DefaultRetryStatusCodes = []int{
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+type RetryPolicy interface {
+	// 最大尝试次数（包括第一次请求）。
+	MaxAttempts() uint32
+	Retryable(httpResp *http.Response, err error) bool
+	// 第 retries+1 次重试之前需要等待的时间。
+	Backoff(retries uint32, httpResp *http.Response) time.Duration
+	String() string
+}
+
+type myRetryPolicy struct {
+	maxAttempts uint32
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	statusCodes map[int]bool
+	description string
+}
+
+func NewRetryPolicy(maxAttempts uint32, baseDelay time.Duration, maxDelay time.Duration, statusCodes []int) RetryPolicy {
+	if statusCodes == nil {
+		statusCodes = DefaultRetryStatusCodes
+	}
+	codes := make(map[int]bool)
+	for _, code := range statusCodes {
+		codes[code] = true
+	}
+	sortedCodes := make([]int, 0, len(codes))
+	for code := range codes {
+		sortedCodes = append(sortedCodes, code)
+	}
+	sort.Ints(sortedCodes)
+	return &myRetryPolicy{
+		maxAttempts: maxAttempts,
+		baseDelay:   baseDelay,
+		maxDelay:    maxDelay,
+		statusCodes: codes,
+		description: fmt.Sprintf(retryPolicyTemplate, maxAttempts, baseDelay, maxDelay, sortedCodes),
+	}
+}
+
+func (policy *myRetryPolicy) MaxAttempts() uint32 {
+	return policy.maxAttempts
+}
+
+func (policy *myRetryPolicy) Retryable(httpResp *http.Response, err error) bool {
+	if err != nil {
+		if urlErr, ok := err.(*url.Error); ok {
+			err = urlErr.Err
+		}
+		if _, ok := err.(net.Error); ok {
+			return true
+		}
+		return err == io.EOF || err == io.ErrUnexpectedEOF
+	}
+	return httpResp != nil && policy.statusCodes[httpResp.StatusCode]
+}
+
+// 指数退避并加入随机抖动，若响应中带有 Retry-After 则以其为准。
+func (policy *myRetryPolicy) Backoff(retries uint32, httpResp *http.Response) time.Duration {
+	if retryAfter := parseRetryAfter(httpResp); retryAfter > 0 {
+		if policy.maxDelay > 0 && retryAfter > policy.maxDelay {
+			return policy.maxDelay
+		}
+		return retryAfter
+	}
+	delay := policy.baseDelay
+	for i := uint32(0); i < retries && (policy.maxDelay <= 0 || delay < policy.maxDelay); i++ {
+		delay *= 2
+	}
+	if policy.maxDelay > 0 && delay > policy.maxDelay {
+		delay = policy.maxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
+
+func parseRetryAfter(httpResp *http.Response) time.Duration {
+	if httpResp == nil {
+		return 0
+	}
+	value := httpResp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+var retryPolicyTemplate = "{ maxAttempts: %d, baseDelay: %s, maxDelay: %s, statusCodes: %v }"
+
+func (policy *myRetryPolicy) String() string {
+	return policy.description
+}
+
+// 表示请求失败但可以在 Delay() 之后重试的错误。
+type RetryError interface {
+	Delay() time.Duration
+	// 响应的状态码，没有响应时为0。
+	StatusCode() int
+	// 是否由超时引起。
+	Timeout() bool
+	// 从发出请求到收到响应头或出错的时间。
+	Latency() time.Duration
+	Error() string
+}
+
+type myRetryError struct {
+	delay      time.Duration
+	statusCode int
+	timeout    bool
+	latency    time.Duration
+	cause      string
+}
+
+func newRetryError(delay time.Duration, statusCode int, timeout bool, latency time.Duration, cause string) RetryError {
+	return &myRetryError{delay: delay, statusCode: statusCode, timeout: timeout, latency: latency, cause: cause}
+}
+
+func (re *myRetryError) Delay() time.Duration {
+	return re.delay
+}
+
+func (re *myRetryError) StatusCode() int {
+	return re.statusCode
+}
+
+func (re *myRetryError) Timeout() bool {
+	return re.timeout
+}
+
+func (re *myRetryError) Latency() time.Duration {
+	return re.latency
+}
+
+func (re *myRetryError) Error() string {
+	return fmt.Sprintf("Retry after %s: %s", re.delay, re.cause)
+}
+
+// 判断下载错误是否由超时引起。
+func IsTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	if re, ok := err.(RetryError); ok {
+		return re.Timeout()
+	}
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	if err == context.DeadlineExceeded {
+		return true
+	}
+	netErr, ok := err.(net.Error)
+	return ok && netErr.Timeout()
+}
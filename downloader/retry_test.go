@@ -0,0 +1,85 @@
+package downloader
+
+import (
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	policy := NewRetryPolicy(5, 100*time.Millisecond, time.Second, nil)
+	cases := []struct {
+		retries uint32
+		max     time.Duration
+	}{
+		{0, 100 * time.Millisecond},
+		{1, 200 * time.Millisecond},
+		{2, 400 * time.Millisecond},
+		{3, 800 * time.Millisecond},
+		// 超过 maxDelay 之后不再增长。
+		{4, time.Second},
+		{30, time.Second},
+	}
+	for _, c := range cases {
+		for i := 0; i < 20; i++ {
+			// 抖动后的等待时间在 [max/2, max] 之间。
+			if delay := policy.Backoff(c.retries, nil); delay < c.max/2 || delay > c.max {
+				t.Fatalf("Backoff(%d) = %s, want in [%s, %s]", c.retries, delay, c.max/2, c.max)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyRetryAfter(t *testing.T) {
+	policy := NewRetryPolicy(3, 100*time.Millisecond, 10*time.Second, nil)
+	httpResp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"3"}}}
+	if delay := policy.Backoff(0, httpResp); delay != 3*time.Second {
+		t.Fatalf("Backoff() = %s, want the Retry-After value", delay)
+	}
+	httpResp.Header.Set("Retry-After", "120")
+	if delay := policy.Backoff(0, httpResp); delay != 10*time.Second {
+		t.Fatalf("Backoff() = %s, want it capped at maxDelay", delay)
+	}
+	// 已经过去的日期被忽略。
+	httpResp.Header.Set("Retry-After", time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat))
+	if delay := policy.Backoff(0, httpResp); delay < 50*time.Millisecond || delay > 100*time.Millisecond {
+		t.Fatalf("Backoff() = %s, a past Retry-After should be ignored", delay)
+	}
+}
+
+func TestRetryPolicyRetryable(t *testing.T) {
+	policy := NewRetryPolicy(3, time.Second, time.Minute, nil)
+	for _, code := range DefaultRetryStatusCodes {
+		if !policy.Retryable(&http.Response{StatusCode: code}, nil) {
+			t.Errorf("Status %d should be retryable by default", code)
+		}
+	}
+	for _, code := range []int{200, 301, 404, 501} {
+		if policy.Retryable(&http.Response{StatusCode: code}, nil) {
+			t.Errorf("Status %d should not be retryable", code)
+		}
+	}
+	custom := NewRetryPolicy(3, time.Second, time.Minute, []int{404})
+	if !custom.Retryable(&http.Response{StatusCode: 404}, nil) || custom.Retryable(&http.Response{StatusCode: 503}, nil) {
+		t.Errorf("The custom status list is not used")
+	}
+	netErr := &url.Error{Op: "Get", URL: "http://a.com/", Err: &net.DNSError{Err: "timeout", IsTimeout: true}}
+	if !policy.Retryable(nil, netErr) || !policy.Retryable(nil, io.ErrUnexpectedEOF) {
+		t.Errorf("Network errors should be retryable")
+	}
+	if policy.Retryable(nil, errors.New("bad request")) {
+		t.Errorf("Other errors should not be retryable")
+	}
+}
+
+func TestRetryPolicyString(t *testing.T) {
+	policy := NewRetryPolicy(3, time.Second, time.Minute, []int{503, 429})
+	want := "{ maxAttempts: 3, baseDelay: 1s, maxDelay: 1m0s, statusCodes: [429 503] }"
+	if got := policy.String(); got != want {
+		t.Fatalf("String() = %q, want %q", got, want)
+	}
+}
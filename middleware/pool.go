@@ -19,19 +19,28 @@ type Entity interface {
 }
 
 type Pool interface {
+	// 取出一个实体，没有空闲的实体时等待，对象池被关闭时返回错误。
 	Take() (Entity, error)
 	Return(entity Entity) error
+	// 调整实体总数。缩小时空闲的实体会被立即移除，
+	// 正在使用的实体会在归还时被移除。
+	Resize(total uint32) error
 	Total() uint32
 	Used() uint32
+	// 关闭对象池，唤醒所有等待中的 Take。关闭后仍然可以归还实体。
+	Close()
 }
 
 type myPool struct {
 	total       uint32
 	entityType  reflect.Type
 	genEntity   func() Entity
-	container   chan Entity
-	idContainer map[uint32]bool
+	container   []Entity        // 空闲的实体。
+	idContainer map[uint32]bool // 所有实体，值表示是否空闲。
+	retiring    uint32          // 归还时需要移除的实体数。
+	closed      bool
 	mutex       sync.Mutex
+	cond        *sync.Cond
 }
 
 func NewPool(
@@ -42,29 +51,52 @@ func NewPool(
 		errMsg := fmt.Sprintf("The pool can not be initialized (total=%d)\n", total)
 		return nil, errors.New(errMsg)
 	}
-	size := int(total)
-	container := make(chan Entity, size)
-	idContainer := make(map[uint32]bool)
-	for i := 0; i < size; i++ {
-		newEntity := genEntity()
-		if entityType != reflect.TypeOf(newEntity) {
-			errMsg := fmt.Sprintf("The type of result of function genEntity() is NOT %s\n", entityType)
-			return nil, errors.New(errMsg)
-		}
-		container <- newEntity
-		idContainer[newEntity.Id()] = true
+	pool := &myPool{
+		entityType:  entityType,
+		genEntity:   genEntity,
+		container:   make([]Entity, 0, total),
+		idContainer: make(map[uint32]bool),
+	}
+	pool.cond = sync.NewCond(&pool.mutex)
+	if err := pool.grow(total); err != nil {
+		return nil, err
 	}
-	pool := &myPool{total: total, entityType: entityType, container: container, genEntity: genEntity, idContainer: idContainer}
+	pool.total = total
 	return pool, nil
 }
 
-func (pool *myPool) Take() (Entity, error) {
-	entity, ok := <-pool.container
-	if !ok {
-		return nil, errors.New("The inner container is invalid!")
+// 新增n个实体，调用方需持有锁。
+// 全部生成成功后才放入对象池，出错时对象池保持不变。
+func (pool *myPool) grow(n uint32) error {
+	entities := make([]Entity, 0, n)
+	for i := uint32(0); i < n; i++ {
+		newEntity := pool.genEntity()
+		if pool.entityType != reflect.TypeOf(newEntity) {
+			errMsg := fmt.Sprintf("The type of result of function genEntity() is NOT %s\n", pool.entityType)
+			return errors.New(errMsg)
+		}
+		entities = append(entities, newEntity)
 	}
+	for _, entity := range entities {
+		pool.container = append(pool.container, entity)
+		pool.idContainer[entity.Id()] = true
+	}
+	return nil
+}
+
+func (pool *myPool) Take() (Entity, error) {
 	pool.mutex.Lock()
 	defer pool.mutex.Unlock()
+	for len(pool.container) == 0 && !pool.closed {
+		pool.cond.Wait()
+	}
+	if pool.closed {
+		return nil, errors.New("The pool is closed!\n")
+	}
+	n := len(pool.container)
+	entity := pool.container[n-1]
+	pool.container[n-1] = nil
+	pool.container = pool.container[:n-1]
 	pool.idContainer[entity.Id()] = false
 	return entity, nil
 }
@@ -79,37 +111,79 @@ func (pool *myPool) Return(entity Entity) error {
 	}
 
 	entityId := entity.Id()
-	casResult := pool.compareAndSetForIdContainer(entityId, false, true)
-	if casResult == 1 {
-		pool.container <- entity
-		return nil
-	} else if casResult == 0 {
-		errMsg := fmt.Sprintf("The entity (id=%d) is alreadey in the pool!\n", entityId)
-		return errors.New(errMsg)
-	} else {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	idle, ok := pool.idContainer[entityId]
+	if !ok {
 		errMsg := fmt.Sprintf("The entity (id=%d) is illegal!\n", entityId)
 		return errors.New(errMsg)
 	}
+	if idle {
+		errMsg := fmt.Sprintf("The entity (id=%d) is alreadey in the pool!\n", entityId)
+		return errors.New(errMsg)
+	}
+	if pool.retiring > 0 {
+		pool.retiring--
+		delete(pool.idContainer, entityId)
+		return nil
+	}
+	pool.idContainer[entityId] = true
+	pool.container = append(pool.container, entity)
+	pool.cond.Signal()
+	return nil
 }
 
-func (pool *myPool) compareAndSetForIdContainer(entityId uint32, oldValue bool, newValue bool) int8 {
+func (pool *myPool) Resize(total uint32) error {
+	if total == 0 {
+		errMsg := fmt.Sprintf("The pool can not be resized (total=%d)\n", total)
+		return errors.New(errMsg)
+	}
 	pool.mutex.Lock()
 	defer pool.mutex.Unlock()
-	v, ok := pool.idContainer[entityId]
-	if !ok {
-		return -1
-	}
-	if v != oldValue {
-		return 0
+	// 不计等待移除的实体时的实体数。
+	current := uint32(len(pool.idContainer)) - pool.retiring
+	switch {
+	case total > current:
+		diff := total - current
+		if diff <= pool.retiring {
+			pool.retiring -= diff
+			break
+		}
+		if err := pool.grow(diff - pool.retiring); err != nil {
+			return err
+		}
+		pool.retiring = 0
+		pool.cond.Broadcast()
+	case total < current:
+		excess := current - total
+		for excess > 0 && len(pool.container) > 0 {
+			n := len(pool.container)
+			delete(pool.idContainer, pool.container[n-1].Id())
+			pool.container[n-1] = nil
+			pool.container = pool.container[:n-1]
+			excess--
+		}
+		pool.retiring += excess
 	}
-	pool.idContainer[entityId] = newValue
-	return 1
+	pool.total = total
+	return nil
+}
+
+func (pool *myPool) Close() {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	pool.closed = true
+	pool.cond.Broadcast()
 }
 
 func (pool *myPool) Total() uint32 {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
 	return pool.total
 }
 
 func (pool *myPool) Used() uint32 {
-	return pool.total - uint32(len(pool.container))
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return uint32(len(pool.idContainer) - len(pool.container))
 }
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type testEntity struct {
+	id uint32
+}
+
+func (entity *testEntity) Id() uint32 {
+	return entity.id
+}
+
+type otherEntity struct {
+	testEntity
+}
+
+func newTestPool(t *testing.T, total uint32) (Pool, *func() Entity) {
+	idGen := NewIdGenerator()
+	gen := func() Entity {
+		return &testEntity{id: idGen.GetUint32()}
+	}
+	pool, err := NewPool(total, reflect.TypeOf(&testEntity{}), func() Entity {
+		return gen()
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pool, &gen
+}
+
+// 在另一个goroutine中取出实体，返回接收结果的通道。
+func takeAsync(pool Pool) chan error {
+	result := make(chan error, 1)
+	go func() {
+		_, err := pool.Take()
+		result <- err
+	}()
+	return result
+}
+
+func expectBlocked(t *testing.T, result chan error) {
+	select {
+	case err := <-result:
+		t.Fatalf("Take() returned early (err=%v)", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func expectTaken(t *testing.T, result chan error, wantErr bool) {
+	select {
+	case err := <-result:
+		if (err != nil) != wantErr {
+			t.Fatalf("Take() error = %v, want error: %v", err, wantErr)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Take() is still blocked")
+	}
+}
+
+func TestPoolResizeWithEntitiesOut(t *testing.T) {
+	pool, _ := newTestPool(t, 3)
+	taken := make([]Entity, 0)
+	for i := 0; i < 3; i++ {
+		entity, err := pool.Take()
+		if err != nil {
+			t.Fatal(err)
+		}
+		taken = append(taken, entity)
+	}
+	// 缩小时正在使用的实体在归还时被移除。
+	if err := pool.Resize(1); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Return(taken[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Return(taken[1]); err != nil {
+		t.Fatal(err)
+	}
+	if used := pool.Used(); used != 1 {
+		t.Fatalf("Used() = %d, want 1", used)
+	}
+	result := takeAsync(pool)
+	expectBlocked(t, result)
+
+	// 扩大时先抵消还未移除的实体，再生成新的实体，并唤醒等待者。
+	if err := pool.Resize(3); err != nil {
+		t.Fatal(err)
+	}
+	expectTaken(t, result, false)
+	if total, used := pool.Total(), pool.Used(); total != 3 || used != 2 {
+		t.Fatalf("Total()=%d, Used()=%d, want 3 and 2", total, used)
+	}
+	if err := pool.Return(taken[2]); err != nil {
+		t.Fatal(err)
+	}
+	if used := pool.Used(); used != 1 {
+		t.Fatalf("Used() = %d, want 1", used)
+	}
+}
+
+func TestPoolResizeRollback(t *testing.T) {
+	pool, gen := newTestPool(t, 1)
+	idGen := NewIdGenerator()
+	count := 0
+	// 第二个生成的实体类型错误。
+	*gen = func() Entity {
+		count++
+		if count == 2 {
+			return &otherEntity{}
+		}
+		return &testEntity{id: 1000 + idGen.GetUint32()}
+	}
+	if err := pool.Resize(4); err == nil {
+		t.Fatalf("Resize() should fail when genEntity() returns a wrong type")
+	}
+	if total, used := pool.Total(), pool.Used(); total != 1 || used != 0 {
+		t.Fatalf("Total()=%d, Used()=%d after a failed Resize(), want 1 and 0", total, used)
+	}
+	entity, err := pool.Take()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectBlocked(t, takeAsync(pool))
+	pool.Return(entity)
+}
+
+func TestPoolClose(t *testing.T) {
+	pool, _ := newTestPool(t, 1)
+	entity, err := pool.Take()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := takeAsync(pool)
+	expectBlocked(t, result)
+	pool.Close()
+	expectTaken(t, result, true)
+	if _, err := pool.Take(); err == nil {
+		t.Fatalf("Take() after Close() should fail")
+	}
+	if err := pool.Return(entity); err != nil {
+		t.Fatalf("Return() after Close() failed: %s", err)
+	}
+}
@@ -37,7 +37,7 @@ func NewStopSign() StopSign {
 func (ss *myStopSign) Sign() bool {
 	ss.rwmutex.Lock()
 	defer ss.rwmutex.Unlock()
-	if !ss.signed {
+	if ss.signed {
 		return false
 	}
 	ss.signed = true
@@ -45,6 +45,8 @@ func (ss *myStopSign) Sign() bool {
 }
 
 func (ss *myStopSign) Signed() bool {
+	ss.rwmutex.RLock()
+	defer ss.rwmutex.RUnlock()
 	return ss.signed
 }
 
@@ -85,6 +87,8 @@ func (ss *myStopSign) DealTotal() uint32 {
 }
 
 func (ss *myStopSign) Summary() string {
+	ss.rwmutex.RLock()
+	defer ss.rwmutex.RUnlock()
 	if ss.signed {
 		return fmt.Sprintf("signed: true, dealCount:%v", ss.dealCountMap)
 	} else {
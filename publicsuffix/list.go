@@ -0,0 +1,234 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 15:12:40
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 15:12:40
+ */
+
+// 基于 Public Suffix List (https://publicsuffix.org) 的域名解析。
+//
+// 内置的列表来自同目录下的 public_suffix_list.dat，更新时替换该文件即可：
+//
+//	curl -o public_suffix_list.dat https://publicsuffix.org/list/public_suffix_list.dat
+//
+// 也可以在运行时通过 LoadFile 和 SetDefault 使用更新的列表。
+package publicsuffix
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"golang.org/x/net/idna"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+//go:embed public_suffix_list.dat
+var embeddedList []byte
+
+const (
+	ruleNormal    byte = 1
+	ruleWildcard  byte = 2
+	ruleException byte = 4
+)
+
+type List interface {
+	// 获取主机的公共后缀，icann 表示该后缀是否来自列表的ICANN部分。
+	PublicSuffix(host string) (suffix string, icann bool)
+	// 获取主机的可注册域名（公共后缀加上一级标签），例如 www.a.co.uk 的可注册域名为 a.co.uk。
+	// 对IP地址和localhost之类的单标签主机直接返回该主机。
+	RegistrableDomain(host string) (string, error)
+	Size() int
+}
+
+type myList struct {
+	rules   map[string]byte
+	private map[string]bool
+}
+
+// 解析PSL格式的数据。
+func Parse(reader io.Reader) (List, error) {
+	list := &myList{rules: make(map[string]byte), private: make(map[string]bool)}
+	private := false
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "//") {
+			if strings.Contains(line, "===BEGIN PRIVATE DOMAINS===") {
+				private = true
+			} else if strings.Contains(line, "===END PRIVATE DOMAINS===") {
+				private = false
+			}
+			continue
+		}
+		if line == "" {
+			continue
+		}
+		if index := strings.IndexAny(line, " \t"); index >= 0 {
+			line = line[:index]
+		}
+		line = strings.ToLower(line)
+		var key string
+		var flag byte
+		switch {
+		case strings.HasPrefix(line, "!"):
+			key, flag = line[1:], ruleException
+		case strings.HasPrefix(line, "*."):
+			key, flag = line[2:], ruleWildcard
+		default:
+			key, flag = line, ruleNormal
+		}
+		// 列表中的国际化域名使用Unicode，统一转为Punycode。
+		if ascii, err := idna.ToASCII(key); err == nil {
+			key = ascii
+		}
+		list.rules[key] |= flag
+		if private {
+			list.private[key] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(list.rules) == 0 {
+		return nil, errors.New("The public suffix list is empty!")
+	}
+	return list, nil
+}
+
+func LoadFile(path string) (List, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return Parse(file)
+}
+
+// 规范化主机名：去掉端口、IPv6的方括号和末尾的点，转为小写，国际化域名转为Punycode。
+func normalizeHost(host string) string {
+	host = strings.ToLower(strings.TrimSpace(host))
+	if strings.HasPrefix(host, "[") {
+		if index := strings.Index(host, "]"); index > 0 {
+			return host[1:index]
+		}
+	}
+	if strings.Count(host, ":") == 1 {
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+	}
+	host = strings.TrimSuffix(host, ".")
+	if ascii, err := idna.ToASCII(host); err == nil {
+		host = strings.ToLower(ascii)
+	}
+	return host
+}
+
+// 返回公共后缀所占的标签数。
+func (list *myList) suffixLabels(labels []string) (int, string) {
+	n := len(labels)
+	count := 1
+	matched := labels[n-1]
+	for i := 0; i < n; i++ {
+		candidate := strings.Join(labels[i:], ".")
+		flag := list.rules[candidate]
+		if flag&ruleException != 0 {
+			return n - i - 1, candidate
+		}
+		if flag&ruleNormal != 0 && n-i > count {
+			count, matched = n-i, candidate
+		}
+		if i > 0 && flag&ruleWildcard != 0 && n-i+1 > count {
+			count, matched = n-i+1, candidate
+		}
+	}
+	return count, matched
+}
+
+func (list *myList) PublicSuffix(host string) (string, bool) {
+	host = normalizeHost(host)
+	if host == "" || net.ParseIP(host) != nil {
+		return host, false
+	}
+	labels := strings.Split(host, ".")
+	count, rule := list.suffixLabels(labels)
+	icann := list.rules[rule] != 0 && !list.private[rule]
+	return strings.Join(labels[len(labels)-count:], "."), icann
+}
+
+func (list *myList) RegistrableDomain(host string) (string, error) {
+	host = normalizeHost(host)
+	if host == "" {
+		return "", errors.New("The host is empty!")
+	}
+	if net.ParseIP(host) != nil {
+		return host, nil
+	}
+	labels := strings.Split(host, ".")
+	for _, label := range labels {
+		if label == "" {
+			return "", fmt.Errorf("The host '%s' has an empty label!", host)
+		}
+	}
+	// 单标签的主机（如localhost）不属于任何公共后缀，视为其自身的可注册域名。
+	if len(labels) == 1 {
+		return host, nil
+	}
+	count, _ := list.suffixLabels(labels)
+	if count >= len(labels) {
+		return "", fmt.Errorf("The host '%s' is a public suffix!", host)
+	}
+	return strings.Join(labels[len(labels)-count-1:], "."), nil
+}
+
+func (list *myList) Size() int {
+	return len(list.rules)
+}
+
+var (
+	defaultList  List
+	defaultMutex sync.RWMutex
+	defaultOnce  sync.Once
+)
+
+// 获取默认的列表，首次调用时解析内置的数据文件。
+func Default() List {
+	defaultOnce.Do(func() {
+		list, err := Parse(bytes.NewReader(embeddedList))
+		if err != nil {
+			panic(fmt.Errorf("The embedded public suffix list is invalid: %s", err))
+		}
+		defaultMutex.Lock()
+		if defaultList == nil {
+			defaultList = list
+		}
+		defaultMutex.Unlock()
+	})
+	defaultMutex.RLock()
+	defer defaultMutex.RUnlock()
+	return defaultList
+}
+
+// 替换默认的列表，例如使用 LoadFile 加载的更新版本。
+func SetDefault(list List) {
+	if list == nil {
+		return
+	}
+	defaultMutex.Lock()
+	defer defaultMutex.Unlock()
+	defaultList = list
+}
+
+func PublicSuffix(host string) (string, bool) {
+	return Default().PublicSuffix(host)
+}
+
+func RegistrableDomain(host string) (string, error) {
+	return Default().RegistrableDomain(host)
+}
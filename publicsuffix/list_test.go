@@ -0,0 +1,78 @@
+package publicsuffix
+
+import (
+	"testing"
+)
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		host   string
+		domain string
+		err    bool
+	}{
+		{"www.example.com", "example.com", false},
+		{"a.b.example.co.uk", "example.co.uk", false},
+		{"example.co.uk", "example.co.uk", false},
+		{"co.uk", "", true},
+		{"user.github.io", "user.github.io", false},
+		{"a.user.github.io", "user.github.io", false},
+		{"github.io", "", true},
+		{"foo.xn--gmqw5a.xn--j6w193g", "foo.xn--gmqw5a.xn--j6w193g", false},
+		{"www.foo.個人.香港", "foo.xn--gmqw5a.xn--j6w193g", false},
+		{"www.example.xn--p1ai", "example.xn--p1ai", false},
+		{"www.пример.рф", "xn--e1afmkfd.xn--p1ai", false},
+		{"[::1]", "::1", false},
+		{"[2001:db8::1]:8080", "2001:db8::1", false},
+		{"127.0.0.1", "127.0.0.1", false},
+		{"127.0.0.1:8080", "127.0.0.1", false},
+		{"www.example.com:8080", "example.com", false},
+		{"WWW.Example.COM.", "example.com", false},
+		{"localhost", "localhost", false},
+		{"localhost:8080", "localhost", false},
+		{"foo.unknowntld", "foo.unknowntld", false},
+		{"www.ck", "www.ck", false},
+		{"a.b.ck", "a.b.ck", false},
+		{"", "", true},
+		{"a..com", "", true},
+	}
+	for _, test := range tests {
+		domain, err := RegistrableDomain(test.host)
+		if test.err {
+			if err == nil {
+				t.Errorf("RegistrableDomain(%q) = %q, want an error", test.host, domain)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("RegistrableDomain(%q) error: %s", test.host, err)
+			continue
+		}
+		if domain != test.domain {
+			t.Errorf("RegistrableDomain(%q) = %q, want %q", test.host, domain, test.domain)
+		}
+	}
+}
+
+func TestPublicSuffix(t *testing.T) {
+	tests := []struct {
+		host   string
+		suffix string
+		icann  bool
+	}{
+		{"www.example.com", "com", true},
+		{"www.example.co.uk", "co.uk", true},
+		{"user.github.io", "github.io", false},
+		{"example.xn--p1ai", "xn--p1ai", true},
+		{"foo.xn--gmqw5a.xn--j6w193g", "xn--gmqw5a.xn--j6w193g", true},
+		{"a.b.ck", "b.ck", true},
+		{"www.ck", "ck", true},
+		{"foo.unknowntld", "unknowntld", false},
+		{"[::1]:80", "::1", false},
+	}
+	for _, test := range tests {
+		suffix, icann := PublicSuffix(test.host)
+		if suffix != test.suffix || icann != test.icann {
+			t.Errorf("PublicSuffix(%q) = (%q, %v), want (%q, %v)", test.host, suffix, icann, test.suffix, test.icann)
+		}
+	}
+}
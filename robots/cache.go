@@ -0,0 +1,175 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 11:52:09
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 11:52:09
+ */
+
+package robots
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxRobotsSize = 500 * 1024       // robots.txt 的最大读取长度。
+	fetchTimeout  = 30 * time.Second // 下载 robots.txt 的超时时间。
+	failureTTL    = time.Minute      // 下载失败（全部禁止）的结果的缓存时间，过期后重新下载。
+)
+
+type RobotsCache interface {
+	// 获取reqUrl所在主机的robots规则，必要时先下载robots.txt。
+	// 下载会在ctx被取消时中止，此时的结果不会被缓存。
+	// 下载失败时返回全部禁止的规则和错误，缓存期间的每次调用都会返回同样的错误。
+	Get(ctx context.Context, reqUrl *url.URL) (Robots, error)
+	Allowed(ctx context.Context, reqUrl *url.URL) (bool, error)
+	UserAgent() string
+	Summary() string
+}
+
+type robotsEntry struct {
+	robots    Robots
+	fetchedAt time.Time
+	err       error // 下载失败的错误，此时缓存的是全部禁止的规则。
+	mutex     sync.Mutex
+}
+
+// 缓存的规则是否仍然有效。
+func (entry *robotsEntry) valid(ttl time.Duration) bool {
+	if entry.robots == nil {
+		return false
+	}
+	if entry.err != nil {
+		if ttl > 0 && ttl < failureTTL {
+			return time.Since(entry.fetchedAt) < ttl
+		}
+		return time.Since(entry.fetchedAt) < failureTTL
+	}
+	return ttl <= 0 || time.Since(entry.fetchedAt) < ttl
+}
+
+type myRobotsCache struct {
+	client    *http.Client
+	userAgent string
+	ttl       time.Duration
+	entries   map[string]*robotsEntry
+	mutex     sync.Mutex
+}
+
+func NewRobotsCache(client *http.Client, userAgent string, ttl time.Duration) RobotsCache {
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &myRobotsCache{
+		client:    client,
+		userAgent: userAgent,
+		ttl:       ttl,
+		entries:   make(map[string]*robotsEntry),
+	}
+}
+
+func (rc *myRobotsCache) getEntry(key string) *robotsEntry {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok {
+		entry = &robotsEntry{}
+		rc.entries[key] = entry
+	}
+	return entry
+}
+
+func (rc *myRobotsCache) Get(ctx context.Context, reqUrl *url.URL) (Robots, error) {
+	if reqUrl == nil || reqUrl.Host == "" {
+		return nil, errors.New("The request url is invalid!\n")
+	}
+	key := strings.ToLower(reqUrl.Scheme + "://" + reqUrl.Host)
+	entry := rc.getEntry(key)
+	entry.mutex.Lock()
+	defer entry.mutex.Unlock()
+	if entry.valid(rc.ttl) {
+		return entry.robots, entry.err
+	}
+	// 同一主机的其他请求会等待这次下载，因此下载需要有超时时间。
+	fetchCtx, cancel := context.WithTimeout(ctx, fetchTimeout)
+	robots, err := rc.fetch(fetchCtx, key)
+	cancel()
+	if ctx.Err() != nil {
+		return robots, err
+	}
+	entry.robots = robots
+	entry.fetchedAt = time.Now()
+	entry.err = err
+	return robots, err
+}
+
+// 下载并解析robots.txt。
+// 4xx 视为没有限制，5xx 和网络错误视为全部禁止，并在 failureTTL 之后重试。
+func (rc *myRobotsCache) fetch(ctx context.Context, siteUrl string) (Robots, error) {
+	robotsUrl := siteUrl + "/robots.txt"
+	httpReq, err := http.NewRequest("GET", robotsUrl, nil)
+	if err != nil {
+		return DisallowAll(), err
+	}
+	httpReq = httpReq.WithContext(ctx)
+	if rc.userAgent != "" {
+		httpReq.Header.Set("User-Agent", rc.userAgent)
+	}
+	httpResp, err := rc.client.Do(httpReq)
+	if err != nil {
+		errMsg := fmt.Sprintf("Fetch robots.txt error (url=%s): %s\n", robotsUrl, err)
+		return DisallowAll(), errors.New(errMsg)
+	}
+	defer httpResp.Body.Close()
+	switch {
+	case httpResp.StatusCode >= 200 && httpResp.StatusCode < 300:
+		robots, err := Parse(io.LimitReader(httpResp.Body, maxRobotsSize), rc.userAgent)
+		if err != nil {
+			errMsg := fmt.Sprintf("Parse robots.txt error (url=%s): %s\n", robotsUrl, err)
+			return DisallowAll(), errors.New(errMsg)
+		}
+		return robots, nil
+	case httpResp.StatusCode >= 400 && httpResp.StatusCode < 500:
+		return AllowAll(), nil
+	default:
+		errMsg := fmt.Sprintf("Unexpected status code %d for robots.txt (url=%s)\n", httpResp.StatusCode, robotsUrl)
+		return DisallowAll(), errors.New(errMsg)
+	}
+}
+
+func (rc *myRobotsCache) Allowed(ctx context.Context, reqUrl *url.URL) (bool, error) {
+	robots, err := rc.Get(ctx, reqUrl)
+	if robots == nil {
+		return false, err
+	}
+	return robots.Allowed(RequestPath(reqUrl)), err
+}
+
+// 获取用于匹配robots规则的路径（包含查询字符串）。
+func RequestPath(reqUrl *url.URL) string {
+	path := reqUrl.EscapedPath()
+	if reqUrl.RawQuery != "" {
+		path += "?" + reqUrl.RawQuery
+	}
+	return path
+}
+
+func (rc *myRobotsCache) UserAgent() string {
+	return rc.userAgent
+}
+
+var robotsCacheSummaryTemplate = "userAgent: %s, hosts: %d"
+
+func (rc *myRobotsCache) Summary() string {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return fmt.Sprintf(robotsCacheSummaryTemplate, rc.userAgent, len(rc.entries))
+}
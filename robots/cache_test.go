@@ -0,0 +1,99 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRobotsCacheStatus(t *testing.T) {
+	tests := []struct {
+		status  int
+		allowed bool
+		err     bool
+	}{
+		{http.StatusOK, false, false},
+		{http.StatusNotFound, true, false},
+		{http.StatusForbidden, true, false},
+		{http.StatusInternalServerError, false, true},
+	}
+	for _, test := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(test.status)
+			fmt.Fprint(w, "User-agent: *\nDisallow: /\n")
+		}))
+		rc := NewRobotsCache(server.Client(), "webcrawler", 0)
+		reqUrl, _ := url.Parse(server.URL + "/page")
+		allowed, err := rc.Allowed(context.Background(), reqUrl)
+		if allowed != test.allowed || (err != nil) != test.err {
+			t.Errorf("status %d: Allowed() = (%v, %v), want (%v, error=%v)", test.status, allowed, err, test.allowed, test.err)
+		}
+		server.Close()
+	}
+}
+
+func TestRobotsCacheRetryFailure(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&fetches, 1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+	}))
+	defer server.Close()
+	// ttl为0（永不过期）时失败的结果也只缓存 failureTTL。
+	rc := NewRobotsCache(server.Client(), "webcrawler", 0).(*myRobotsCache)
+	reqUrl, _ := url.Parse(server.URL + "/page")
+	if allowed, err := rc.Allowed(context.Background(), reqUrl); allowed || err == nil {
+		t.Fatalf("Allowed() = (%v, %v), want a failure", allowed, err)
+	}
+	if allowed, err := rc.Allowed(context.Background(), reqUrl); allowed || err == nil {
+		t.Fatalf("The failure should be cached with its error: (%v, %v)", allowed, err)
+	}
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Fatalf("fetches = %d, want 1", n)
+	}
+
+	for _, entry := range rc.entries {
+		entry.fetchedAt = time.Now().Add(-failureTTL)
+	}
+	if allowed, err := rc.Allowed(context.Background(), reqUrl); !allowed || err != nil {
+		t.Fatalf("Allowed() = (%v, %v) after the failure expired", allowed, err)
+	}
+	for _, entry := range rc.entries {
+		entry.fetchedAt = time.Now().Add(-24 * time.Hour)
+	}
+	if allowed, _ := rc.Allowed(context.Background(), reqUrl); !allowed {
+		t.Fatalf("The successful result should not expire")
+	}
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Fatalf("fetches = %d, want 2", n)
+	}
+}
+
+func TestRobotsCacheCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	rc := NewRobotsCache(server.Client(), "webcrawler", 0).(*myRobotsCache)
+	reqUrl, _ := url.Parse(server.URL + "/page")
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := rc.Get(ctx, reqUrl); err == nil {
+		t.Fatalf("Get() should fail when the context is cancelled")
+	}
+	for _, entry := range rc.entries {
+		if entry.robots != nil {
+			t.Fatalf("The cancelled fetch should not be cached")
+		}
+	}
+}
@@ -0,0 +1,185 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 11:20:46
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 11:20:46
+ */
+
+package robots
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Robots interface {
+	Allowed(path string) bool
+	CrawlDelay() time.Duration
+	Sitemaps() []string
+}
+
+type rule struct {
+	allow   bool
+	pattern string
+	regexp  *regexp.Regexp
+}
+
+type group struct {
+	agents     []string
+	rules      []*rule
+	crawlDelay time.Duration
+	hasDelay   bool
+}
+
+type myRobots struct {
+	rules      []*rule
+	crawlDelay time.Duration
+	sitemaps   []string
+}
+
+func AllowAll() Robots {
+	return &myRobots{rules: make([]*rule, 0), sitemaps: make([]string, 0)}
+}
+
+func DisallowAll() Robots {
+	rules := []*rule{newRule(false, "/")}
+	return &myRobots{rules: rules, sitemaps: make([]string, 0)}
+}
+
+// 获取User-Agent中的产品标识，例如 "MyBot/1.0 (+http://a.com)" 的产品标识为 "mybot"。
+func productToken(userAgent string) string {
+	token := strings.TrimSpace(userAgent)
+	if index := strings.IndexAny(token, "/ "); index >= 0 {
+		token = token[:index]
+	}
+	return strings.ToLower(token)
+}
+
+func newRule(allow bool, pattern string) *rule {
+	expr := regexp.QuoteMeta(pattern)
+	expr = strings.Replace(expr, `\*`, ".*", -1)
+	if strings.HasSuffix(expr, `\$`) {
+		expr = expr[:len(expr)-2] + "$"
+	}
+	return &rule{allow: allow, pattern: pattern, regexp: regexp.MustCompile("^" + expr)}
+}
+
+// 解析robots.txt的内容，并选出适用于userAgent的规则组。
+// 若没有与userAgent匹配的规则组，则使用 "*" 规则组。
+func Parse(body io.Reader, userAgent string) (Robots, error) {
+	token := productToken(userAgent)
+	groups := make([]*group, 0)
+	sitemaps := make([]string, 0)
+	var current *group
+	inAgents := false
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if index := strings.Index(line, "#"); index >= 0 {
+			line = line[:index]
+		}
+		index := strings.Index(line, ":")
+		if index < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:index]))
+		value := strings.TrimSpace(line[index+1:])
+		switch key {
+		case "user-agent":
+			if !inAgents || current == nil {
+				current = &group{agents: make([]string, 0), rules: make([]*rule, 0)}
+				groups = append(groups, current)
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+			inAgents = true
+		case "allow", "disallow":
+			inAgents = false
+			if current == nil || value == "" {
+				continue
+			}
+			current.rules = append(current.rules, newRule(key == "allow", value))
+		case "crawl-delay":
+			inAgents = false
+			if current == nil {
+				continue
+			}
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil || seconds < 0 {
+				continue
+			}
+			current.crawlDelay = time.Duration(seconds * float64(time.Second))
+			current.hasDelay = true
+		case "sitemap":
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	robots := &myRobots{rules: make([]*rule, 0), sitemaps: sitemaps}
+	matched := selectGroups(groups, token)
+	if len(matched) == 0 {
+		matched = selectGroups(groups, "*")
+	}
+	for _, g := range matched {
+		robots.rules = append(robots.rules, g.rules...)
+		if g.hasDelay && g.crawlDelay > robots.crawlDelay {
+			robots.crawlDelay = g.crawlDelay
+		}
+	}
+	return robots, nil
+}
+
+func selectGroups(groups []*group, token string) []*group {
+	result := make([]*group, 0)
+	if token == "" {
+		return result
+	}
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == token {
+				result = append(result, g)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// 使用最长匹配原则判断路径是否允许被抓取，长度相同时Allow优先。
+func (robots *myRobots) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	if path == "/robots.txt" {
+		return true
+	}
+	var matched *rule
+	for _, r := range robots.rules {
+		if !r.regexp.MatchString(path) {
+			continue
+		}
+		if matched == nil ||
+			len(r.pattern) > len(matched.pattern) ||
+			(len(r.pattern) == len(matched.pattern) && r.allow) {
+			matched = r
+		}
+	}
+	return matched == nil || matched.allow
+}
+
+func (robots *myRobots) CrawlDelay() time.Duration {
+	return robots.crawlDelay
+}
+
+func (robots *myRobots) Sitemaps() []string {
+	return robots.sitemaps
+}
@@ -0,0 +1,64 @@
+package robots
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRobots = `
+User-agent: *
+Disallow: /private/
+Allow: /private/public
+Crawl-delay: 1
+
+User-agent: webcrawler
+Disallow: /search
+Disallow: /*.pdf$
+Allow: /search/about
+Crawl-delay: 2.5
+
+Sitemap: http://example.com/sitemap.xml
+`
+
+func TestParseAllowed(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		path      string
+		allowed   bool
+	}{
+		{"webcrawler/1.0", "/", true},
+		{"webcrawler/1.0", "/search?q=go", false},
+		{"webcrawler/1.0", "/search/about", true},
+		{"webcrawler/1.0", "/doc/a.pdf", false},
+		{"webcrawler/1.0", "/doc/a.pdf?x=1", true},
+		{"webcrawler/1.0", "/private/", true},
+		{"webcrawler/1.0", "/robots.txt", true},
+		{"other", "/private/a", false},
+		{"other", "/private/public", true},
+		{"other", "/search", true},
+	}
+	for _, test := range tests {
+		robots, err := Parse(strings.NewReader(testRobots), test.userAgent)
+		if err != nil {
+			t.Fatalf("Parse error: %s", err)
+		}
+		if allowed := robots.Allowed(test.path); allowed != test.allowed {
+			t.Errorf("Allowed(%q) for %q = %v, want %v", test.path, test.userAgent, allowed, test.allowed)
+		}
+	}
+}
+
+func TestParseCrawlDelayAndSitemaps(t *testing.T) {
+	robots, err := Parse(strings.NewReader(testRobots), "WebCrawler")
+	if err != nil {
+		t.Fatalf("Parse error: %s", err)
+	}
+	if delay := robots.CrawlDelay(); delay != 2500*time.Millisecond {
+		t.Errorf("CrawlDelay() = %s, want 2.5s", delay)
+	}
+	sitemaps := robots.Sitemaps()
+	if len(sitemaps) != 1 || sitemaps[0] != "http://example.com/sitemap.xml" {
+		t.Errorf("Sitemaps() = %v", sitemaps)
+	}
+}
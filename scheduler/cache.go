@@ -21,6 +21,7 @@ var statusMap = map[byte]string{
 type requestCache interface {
 	put(req *base.Request) bool
 	get() *base.Request
+	done(req *base.Request)
 	capacity() int
 	length() int
 	close()
@@ -28,14 +29,16 @@ type requestCache interface {
 }
 
 type reqCacheBySlice struct {
-	cache  []*base.Request
+	cache  frontier
+	order  FrontierOrder
 	mutex  sync.Mutex
 	status byte
 }
 
-func newRequestCache() requestCache {
+func newRequestCache(order FrontierOrder) requestCache {
 	rc := &reqCacheBySlice{
-		cache: make([]*base.Request, 0),
+		cache: newFrontier(order),
+		order: order,
 	}
 	return rc
 }
@@ -49,7 +52,7 @@ func (rcache *reqCacheBySlice) put(req *base.Request) bool {
 	}
 	rcache.mutex.Lock()
 	defer rcache.mutex.Unlock()
-	rcache.cache = append(rcache.cache, req)
+	rcache.cache.push(req)
 	return true
 }
 
@@ -62,17 +65,22 @@ func (rcache *reqCacheBySlice) get() *base.Request {
 	}
 	rcache.mutex.Lock()
 	defer rcache.mutex.Unlock()
-	req := rcache.cache[0]
-	rcache.cache = rcache.cache[1:]
-	return req
+	return rcache.cache.pop()
+}
+
+func (rcache *reqCacheBySlice) done(req *base.Request) {
 }
 
 func (rcache *reqCacheBySlice) capacity() int {
-	return cap(rcache.cache)
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	return rcache.cache.capacity()
 }
 
 func (rcache *reqCacheBySlice) length() int {
-	return len(rcache.cache)
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	return rcache.cache.len()
 }
 
 func (rcache *reqCacheBySlice) close() {
@@ -82,8 +90,8 @@ func (rcache *reqCacheBySlice) close() {
 	rcache.status = 1
 }
 
-var summaryTemplate = "status: %s, " + "length:%d, " + "capacity:%d"
+var summaryTemplate = "status: %s, " + "order: %s, " + "length:%d, " + "capacity:%d"
 
 func (rcache *reqCacheBySlice) summary() string {
-	return fmt.Sprintf(summaryTemplate, statusMap[rcache.status], rcache.length(), rcache.capacity())
+	return fmt.Sprintf(summaryTemplate, statusMap[rcache.status], rcache.order, rcache.length(), rcache.capacity())
 }
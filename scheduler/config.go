@@ -0,0 +1,154 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 13:48:50
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 13:48:50
+ */
+
+package scheduler
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	anlz "webcrawler/analyzer"
+	base "webcrawler/base"
+	"webcrawler/dedup"
+	dl "webcrawler/downloader"
+	ipl "webcrawler/itempipeline"
+	"webcrawler/warc"
+)
+
+// 调度器的配置。
+// 可选项的零值表示不启用相应的功能。
+type Config struct {
+	ChannelArgs         base.ChannelArgs
+	PoolBaseArgs        base.PoolBaseArgs
+	CrawlDepth          uint32
+	HttpClientGenerator GenhttpClient // 设置了PageDownloaderGenerator时可以为nil。
+	RespParsers         []anlz.ParseResponse
+	ItemProcessors      []ipl.ProcessItem
+	Seeds               []*http.Request // 种子请求，至少需要一个。
+	Scope               Scope
+
+	// 可选项。
+	HostLimitArgs base.HostLimitArgs
+	RobotsArgs    base.RobotsArgs    // User-Agent为空时不检查robots.txt。
+	RetryPolicy   dl.RetryPolicy     // 为nil时不重试。
+	RateLimiter   dl.RateLimiter     // 为nil时不限制请求和带宽的速率。
+	HttpCache     dl.HttpCache       // 为nil时不缓存响应。
+	SkipUnchanged bool               // 是否跳过对内容没有变化（304）的响应的分析，用于增量抓取。
+	WarcWriter    warc.Writer        // 为nil时不归档，由调用方负责关闭。
+	MaxBodySize   int64              // 响应体的最大缓冲长度，0表示 base.DefaultMaxBodySize。
+	CheckpointDir string             // 为空时请求缓存只保存在内存中。
+	Canonicalizer base.Canonicalizer // 为nil时使用默认的规范化规则。
+	SeenSet       dedup.SeenSet      // 为nil时使用内存中的map，由调用方负责关闭。
+	FrontierOrder FrontierOrder      // 请求缓存中请求的取出顺序，默认先进先出。
+	// 自定义的网页下载器的生成函数，为nil时使用HttpClientGenerator生成的默认下载器。
+	// 使用自定义的下载器时RetryPolicy、RateLimiter、HttpCache、WarcWriter和MaxBodySize不会生效，
+	// 需要时由生成函数自行使用，返回的下载器的类型必须相同。
+	PageDownloaderGenerator dl.GenPageDownloader
+	// 下载器中间件，按顺序处理请求，按逆序处理响应和错误。
+	DownloaderMiddlewares []dl.Middleware
+	// 目标延迟为0时不启用自适应限速。
+	AutoThrottleArgs base.AutoThrottleArgs
+}
+
+// 配置检查失败时返回的错误，包含所有的问题。
+type ConfigError interface {
+	Errors() []error
+	Error() string
+}
+
+type myConfigError struct {
+	errs []error
+}
+
+func (ce *myConfigError) Errors() []error {
+	return ce.errs
+}
+
+func (ce *myConfigError) Error() string {
+	var buffer bytes.Buffer
+	buffer.WriteString("Invalid scheduler config:\n")
+	for _, err := range ce.errs {
+		msg := strings.TrimRight(err.Error(), "\n")
+		for _, line := range strings.Split(msg, "\n") {
+			buffer.WriteString("  ")
+			buffer.WriteString(line)
+			buffer.WriteByte('\n')
+		}
+	}
+	return buffer.String()
+}
+
+// 检查配置，若有问题则返回包含全部问题的ConfigError。
+func (config *Config) Check() error {
+	errs := make([]error, 0)
+	appendErr := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	appendErr(config.ChannelArgs.Check())
+	appendErr(config.PoolBaseArgs.Check())
+	if config.HttpClientGenerator == nil && config.PageDownloaderGenerator == nil {
+		appendErr(errors.New("The Http Client generator and page downloader generator are both invalid!\n"))
+	}
+	if config.RespParsers == nil {
+		appendErr(errors.New("The response parser list is invalid!\n"))
+	}
+	for i, parser := range config.RespParsers {
+		if parser == nil {
+			appendErr(fmt.Errorf("The %dth response parser is invalid!\n", i))
+		}
+	}
+	if config.ItemProcessors == nil {
+		appendErr(errors.New("The item processor list is invalid!\n"))
+	}
+	for i, processor := range config.ItemProcessors {
+		if processor == nil {
+			appendErr(fmt.Errorf("The %dth item processor is invalid!\n", i))
+		}
+	}
+	if len(config.Seeds) == 0 {
+		appendErr(errors.New("The seed list is empty!\n"))
+	}
+	for i, seed := range config.Seeds {
+		if seed == nil || seed.URL == nil {
+			appendErr(fmt.Errorf("The %dth seed is invalid!\n", i))
+		} else if len(config.Scope.AllowedDomains) > 0 || config.Scope.Anywhere {
+			continue
+		} else if _, err := getPrimaryDomain(seed.URL.Hostname()); err != nil {
+			appendErr(fmt.Errorf("The host of %dth seed is invalid: %s\n", i, err))
+		}
+	}
+	errs = append(errs, config.Scope.Check()...)
+	appendErr(config.HostLimitArgs.Check())
+	if config.RobotsArgs.UserAgent() != "" {
+		appendErr(config.RobotsArgs.Check())
+	}
+	if config.MaxBodySize < 0 {
+		appendErr(errors.New("The max body size is invalid!\n"))
+	}
+	for i, middleware := range config.DownloaderMiddlewares {
+		if middleware == nil {
+			appendErr(fmt.Errorf("The %dth downloader middleware is invalid!\n", i))
+		}
+	}
+	if config.AutoThrottleArgs.TargetLatency() != 0 {
+		appendErr(config.AutoThrottleArgs.Check())
+	}
+	if _, ok := frontierOrderMap[config.FrontierOrder]; !ok {
+		appendErr(fmt.Errorf("The frontier order %d is unsupported!\n", config.FrontierOrder))
+	}
+	if config.RetryPolicy != nil && config.RetryPolicy.MaxAttempts() == 0 {
+		appendErr(errors.New("The max attempts of retry policy can not be 0!\n"))
+	}
+	if len(errs) > 0 {
+		return &myConfigError{errs: errs}
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 10:02:11
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 10:02:11
+ */
+
+package scheduler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+	base "webcrawler/base"
+	"webcrawler/dedup"
+)
+
+const (
+	frontierFileName = "frontier.log"
+
+	recordOpPut  = "put"
+	recordOpDone = "done"
+	recordOpSeen = "seen"
+)
+
+// 请求日志中的一条记录。
+type reqRecord struct {
+	Op        string      `json:"op"`
+	Url       string      `json:"url"`
+	Canonical string      `json:"canonical,omitempty"`
+	Method    string      `json:"method,omitempty"`
+	Header    http.Header `json:"header,omitempty"`
+	Depth     uint32      `json:"depth,omitempty"`
+	Retries   uint32      `json:"retries,omitempty"`
+	Priority  int         `json:"priority,omitempty"`
+	Parent    string      `json:"parent,omitempty"`
+	Tag       string      `json:"tag,omitempty"`
+}
+
+func newReqRecord(op string, req *base.Request) *reqRecord {
+	httpReq := req.HttpReq()
+	record := &reqRecord{Op: op, Url: httpReq.URL.String()}
+	if canonicalUrl := req.CanonicalUrl(); canonicalUrl != record.Url {
+		record.Canonical = canonicalUrl
+	}
+	if op == recordOpPut {
+		record.Method = httpReq.Method
+		record.Header = httpReq.Header
+		record.Depth = req.Depth()
+		record.Retries = req.Retries()
+		record.Priority = req.Priority()
+		record.Parent = req.ParentUrl()
+		record.Tag = req.Tag()
+	}
+	return record
+}
+
+// 用于去重的键。
+func (record *reqRecord) key() string {
+	if record.Canonical != "" {
+		return record.Canonical
+	}
+	return record.Url
+}
+
+func (record *reqRecord) toRequest() (*base.Request, error) {
+	httpReq, err := http.NewRequest(record.Method, record.Url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if record.Header != nil {
+		httpReq.Header = record.Header
+	}
+	req := base.NewRequest(httpReq, record.Depth)
+	req.SetRetries(record.Retries)
+	req.SetCanonicalUrl(record.Canonical)
+	req.SetPriority(record.Priority)
+	req.SetParentUrl(record.Parent)
+	req.SetTag(record.Tag)
+	return req, nil
+}
+
+// 日志中的记录数达到上次压缩后的两倍且至少增加了这么多条时压缩日志。
+const compactMinRecords = 10000
+
+// 把请求日志同步到磁盘的间隔。
+const checkpointSyncInterval = time.Second
+
+// 基于文件的请求缓存。
+// 所有放入的请求都会被追加到检查点目录下的请求日志中，
+// 处理完成的请求会被标记为完成，以便在中断后恢复未完成的请求和已见过的URL。
+// 已见过的URL只保存在日志和去重集合中，日志在运行过程中会被定期压缩。
+type reqCacheByFile struct {
+	dir       string
+	cache     frontier
+	order     FrontierOrder
+	taken     map[string]*base.Request // 已被取出但还未完成的请求。
+	records   int                      // 日志中的记录数。
+	compacted int                      // 上次压缩后日志中的记录数。
+	synced    time.Time                // 上次同步到磁盘的时间。
+	onError   func(err error)          // 用于报告写入日志失败的函数。
+	failed    bool                     // 是否已经报告过写入失败。
+	file      *os.File
+	writer    *bufio.Writer
+	encoder   *json.Encoder
+	mutex     sync.Mutex
+	status    byte
+}
+
+// 创建基于文件的请求缓存，日志中已见过的URL会被加入seenSet。
+// 写入日志失败时会调用onError，之后的检查点不再可靠。
+func newFileRequestCache(dir string, order FrontierOrder, seenSet dedup.SeenSet, onError func(err error)) (*reqCacheByFile, error) {
+	if dir == "" {
+		return nil, errors.New("The checkpoint directory is empty!\n")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	rcache := &reqCacheByFile{
+		dir:     dir,
+		cache:   newFrontier(order),
+		order:   order,
+		taken:   make(map[string]*base.Request),
+		onError: onError,
+	}
+	if err := rcache.load(seenSet); err != nil {
+		return nil, err
+	}
+	if err := rcache.compact(); err != nil {
+		return nil, err
+	}
+	return rcache, nil
+}
+
+func (rcache *reqCacheByFile) path() string {
+	return filepath.Join(rcache.dir, frontierFileName)
+}
+
+// 请求在日志中的键。
+func requestKey(req *base.Request) string {
+	if canonicalUrl := req.CanonicalUrl(); canonicalUrl != "" {
+		return canonicalUrl
+	}
+	return req.HttpReq().URL.String()
+}
+
+// 重放请求日志，恢复待处理的请求，并把见过的URL逐条加入seenSet。
+func (rcache *reqCacheByFile) load(seenSet dedup.SeenSet) error {
+	file, err := os.Open(rcache.path())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	pending := make(map[string]*reqRecord)
+	order := make([]string, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		var record reqRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			// 进程崩溃时最后一行可能只写入了一半。
+			logger.Warnf("Ignore the broken checkpoint record! (file=%s, line=%d): %s\n", rcache.path(), line, err)
+			continue
+		}
+		key := record.key()
+		switch record.Op {
+		case recordOpPut:
+			if _, ok := pending[key]; !ok {
+				order = append(order, key)
+			}
+			pending[key] = &record
+		case recordOpDone:
+			delete(pending, key)
+		}
+		if _, err := seenSet.Add(key); err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	for _, key := range order {
+		record, ok := pending[key]
+		if !ok {
+			continue
+		}
+		// 同一个请求可能被多次放入（如重试），只恢复一次。
+		delete(pending, key)
+		req, err := record.toRequest()
+		if err != nil {
+			logger.Warnf("Ignore the invalid checkpoint request! (url=%s): %s\n", record.Url, err)
+			continue
+		}
+		rcache.cache.push(req)
+	}
+	return nil
+}
+
+// 以当前状态重写请求日志，避免其无限增长。调用方需持有锁（或还未开始使用）。
+// 未完成的请求被写为put记录，旧日志中已完成和已见过的URL被逐条复制为seen记录。
+func (rcache *reqCacheByFile) compact() error {
+	if rcache.writer != nil {
+		if err := rcache.writer.Flush(); err != nil {
+			return err
+		}
+	}
+	tmpFile, err := ioutil.TempFile(rcache.dir, frontierFileName+".tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmpFile.Name()
+	records, err := rcache.rewrite(tmpFile)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, rcache.path()); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if rcache.file != nil {
+		rcache.file.Close()
+	}
+	rcache.file, err = os.OpenFile(rcache.path(), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	rcache.writer = bufio.NewWriter(rcache.file)
+	rcache.encoder = json.NewEncoder(rcache.writer)
+	rcache.records = records
+	rcache.compacted = records
+	rcache.synced = time.Now()
+	return nil
+}
+
+// 把压缩后的日志写入file，返回写入的记录数。
+func (rcache *reqCacheByFile) rewrite(file *os.File) (int, error) {
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	records := 0
+	pending := make(map[string]bool)
+	write := func(req *base.Request) error {
+		pending[requestKey(req)] = true
+		records++
+		return encoder.Encode(newReqRecord(recordOpPut, req))
+	}
+	// 已取出的请求在恢复后应最先被取出，后进先出时需要写在最后。
+	taken := make([]*base.Request, 0, len(rcache.taken))
+	for _, req := range rcache.taken {
+		taken = append(taken, req)
+	}
+	reqs := rcache.cache.list()
+	if rcache.order == FRONTIER_LIFO {
+		reqs = append(reqs, taken...)
+	} else {
+		reqs = append(taken, reqs...)
+	}
+	for _, req := range reqs {
+		if err := write(req); err != nil {
+			return records, err
+		}
+	}
+
+	old, err := os.Open(rcache.path())
+	if err != nil && !os.IsNotExist(err) {
+		return records, err
+	}
+	if old != nil {
+		defer old.Close()
+		scanner := bufio.NewScanner(old)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var record reqRecord
+			if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+				continue
+			}
+			key := record.key()
+			if record.Op == recordOpPut || pending[key] {
+				continue
+			}
+			records++
+			if err := encoder.Encode(&reqRecord{Op: recordOpSeen, Url: key}); err != nil {
+				return records, err
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return records, err
+		}
+	}
+	return records, writer.Flush()
+}
+
+// 追加一条记录，并在需要时同步或压缩日志。调用方需持有锁。
+func (rcache *reqCacheByFile) append(record *reqRecord) {
+	if err := rcache.encoder.Encode(record); err != nil {
+		rcache.fail(err)
+		return
+	}
+	rcache.records++
+	if err := rcache.writer.Flush(); err != nil {
+		rcache.fail(err)
+		return
+	}
+	if rcache.records-rcache.compacted >= compactMinRecords && rcache.records >= 2*rcache.compacted {
+		// 压缩失败时旧的日志仍然完整。
+		if err := rcache.compact(); err != nil {
+			logger.Warnf("Failed to compact the checkpoint file! (file=%s): %s\n", rcache.path(), err)
+			// 下次达到阈值时再尝试。
+			rcache.compacted = rcache.records
+		}
+		return
+	}
+	if time.Since(rcache.synced) >= checkpointSyncInterval {
+		if err := rcache.file.Sync(); err != nil {
+			rcache.fail(err)
+		}
+		rcache.synced = time.Now()
+	}
+}
+
+// 报告写入日志失败。写入失败后的记录都不再可靠，因此只报告第一次失败。调用方需持有锁。
+func (rcache *reqCacheByFile) fail(err error) {
+	logger.Warnf("Failed to write the checkpoint file! (file=%s): %s\n", rcache.path(), err)
+	if rcache.failed {
+		return
+	}
+	rcache.failed = true
+	if rcache.onError != nil {
+		errMsg := fmt.Sprintf("Failed to write the checkpoint file '%s', the crawl can not be restored from it: %s\n", rcache.path(), err)
+		rcache.onError(errors.New(errMsg))
+	}
+}
+
+func (rcache *reqCacheByFile) put(req *base.Request) bool {
+	if req == nil || !req.Valid() {
+		return false
+	}
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	if rcache.status == 1 {
+		return false
+	}
+	delete(rcache.taken, requestKey(req))
+	// 先放入再追加记录，以便追加时的压缩包含该请求。
+	rcache.cache.push(req)
+	rcache.append(newReqRecord(recordOpPut, req))
+	return true
+}
+
+func (rcache *reqCacheByFile) get() *base.Request {
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	if rcache.status == 1 {
+		return nil
+	}
+	req := rcache.cache.pop()
+	if req != nil {
+		rcache.taken[requestKey(req)] = req
+	}
+	return req
+}
+
+func (rcache *reqCacheByFile) done(req *base.Request) {
+	if req == nil || !req.Valid() {
+		return
+	}
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	if rcache.status == 1 {
+		return
+	}
+	delete(rcache.taken, requestKey(req))
+	rcache.append(newReqRecord(recordOpDone, req))
+}
+
+func (rcache *reqCacheByFile) capacity() int {
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	return rcache.cache.capacity()
+}
+
+func (rcache *reqCacheByFile) length() int {
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	return rcache.cache.len()
+}
+
+func (rcache *reqCacheByFile) close() {
+	rcache.mutex.Lock()
+	defer rcache.mutex.Unlock()
+	if rcache.status == 1 {
+		return
+	}
+	rcache.status = 1
+	if err := rcache.writer.Flush(); err != nil {
+		logger.Warnf("Failed to flush the checkpoint file! (file=%s): %s\n", rcache.path(), err)
+	}
+	if err := rcache.file.Sync(); err != nil {
+		logger.Warnf("Failed to sync the checkpoint file! (file=%s): %s\n", rcache.path(), err)
+	}
+	rcache.file.Close()
+}
+
+var fileCacheSummaryTemplate = "status: %s, " + "order: %s, " + "length:%d, " + "capacity:%d, " + "checkpoint:%s"
+
+func (rcache *reqCacheByFile) summary() string {
+	return fmt.Sprintf(fileCacheSummaryTemplate, statusMap[rcache.status], rcache.order, rcache.length(), rcache.capacity(), rcache.dir)
+}
@@ -0,0 +1,273 @@
+package scheduler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+	"webcrawler/analyzer"
+	"webcrawler/base"
+	"webcrawler/dedup"
+	dl "webcrawler/downloader"
+	ipl "webcrawler/itempipeline"
+)
+
+func newCheckpointDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "checkpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func newCheckpointRequest(t *testing.T, rawUrl string) *base.Request {
+	httpReq, err := http.NewRequest("GET", rawUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := base.NewRequest(httpReq, 1)
+	req.SetCanonicalUrl(rawUrl)
+	return req
+}
+
+func openFileCache(t *testing.T, dir string, order FrontierOrder, seenSet dedup.SeenSet) *reqCacheByFile {
+	rcache, err := newFileRequestCache(dir, order, seenSet, func(err error) {
+		t.Errorf("Unexpected checkpoint error: %s", err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rcache
+}
+
+// 依次取出缓存中的所有请求的URL。
+func drainUrls(rcache *reqCacheByFile) []string {
+	urls := make([]string, 0)
+	for req := rcache.get(); req != nil; req = rcache.get() {
+		urls = append(urls, req.HttpReq().URL.String())
+	}
+	return urls
+}
+
+// 读取日志中的所有记录。
+func readCheckpoint(t *testing.T, dir string) []reqRecord {
+	file, err := os.Open(filepath.Join(dir, frontierFileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records := make([]reqRecord, 0)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record reqRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestFileCacheReopen(t *testing.T) {
+	for _, order := range []FrontierOrder{FRONTIER_FIFO, FRONTIER_LIFO} {
+		dir := newCheckpointDir(t)
+		defer os.RemoveAll(dir)
+		rcache := openFileCache(t, dir, order, dedup.NewMapSeenSet())
+		for _, rawUrl := range []string{"http://a.com/1", "http://a.com/2", "http://a.com/3", "http://a.com/4"} {
+			rcache.put(newCheckpointRequest(t, rawUrl))
+		}
+		done := rcache.get()
+		rcache.done(done)
+		// 已取出但未完成的请求在恢复后应该被重新处理。
+		taken := rcache.get()
+		want := append([]string{taken.HttpReq().URL.String()}, drainUrls(rcache)...)
+		rcache.close()
+
+		seenSet := dedup.NewMapSeenSet()
+		restored := openFileCache(t, dir, order, seenSet)
+		got := drainUrls(restored)
+		restored.close()
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: restored = %v, want %v", order, got, want)
+		}
+		for _, rawUrl := range []string{"http://a.com/1", "http://a.com/2", "http://a.com/3", "http://a.com/4"} {
+			if seen, err := seenSet.Contains(rawUrl); err != nil || !seen {
+				t.Errorf("%s: %s is not restored into the seen set", order, rawUrl)
+			}
+		}
+	}
+}
+
+func TestFileCacheDoneNotRestored(t *testing.T) {
+	dir := newCheckpointDir(t)
+	defer os.RemoveAll(dir)
+	rcache := openFileCache(t, dir, FRONTIER_FIFO, dedup.NewMapSeenSet())
+	rcache.put(newCheckpointRequest(t, "http://a.com/1"))
+	rcache.put(newCheckpointRequest(t, "http://a.com/2"))
+	for req := rcache.get(); req != nil; req = rcache.get() {
+		rcache.done(req)
+	}
+	rcache.close()
+
+	restored := openFileCache(t, dir, FRONTIER_FIFO, dedup.NewMapSeenSet())
+	defer restored.close()
+	if urls := drainUrls(restored); len(urls) != 0 {
+		t.Fatalf("Done requests are restored: %v", urls)
+	}
+}
+
+func TestFileCacheCompact(t *testing.T) {
+	dir := newCheckpointDir(t)
+	defer os.RemoveAll(dir)
+	rcache := openFileCache(t, dir, FRONTIER_FIFO, dedup.NewMapSeenSet())
+	for _, rawUrl := range []string{"http://a.com/1", "http://a.com/2", "http://a.com/3"} {
+		rcache.put(newCheckpointRequest(t, rawUrl))
+	}
+	rcache.done(rcache.get())
+	// 取出的请求被再次放入（如重试）。
+	rcache.put(rcache.get())
+	rcache.mutex.Lock()
+	err := rcache.compact()
+	rcache.mutex.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	rcache.close()
+
+	puts := make([]string, 0)
+	seen := make([]string, 0)
+	for _, record := range readCheckpoint(t, dir) {
+		switch record.Op {
+		case recordOpPut:
+			puts = append(puts, record.Url)
+		case recordOpSeen:
+			seen = append(seen, record.Url)
+		default:
+			t.Errorf("Unexpected %s record in the compacted checkpoint", record.Op)
+		}
+	}
+	sort.Strings(puts)
+	if want := []string{"http://a.com/2", "http://a.com/3"}; !reflect.DeepEqual(puts, want) {
+		t.Errorf("put records = %v, want %v", puts, want)
+	}
+	if want := []string{"http://a.com/1"}; !reflect.DeepEqual(seen, want) {
+		t.Errorf("seen records = %v, want %v", seen, want)
+	}
+}
+
+func TestFileCacheBrokenLastLine(t *testing.T) {
+	dir := newCheckpointDir(t)
+	defer os.RemoveAll(dir)
+	rcache := openFileCache(t, dir, FRONTIER_FIFO, dedup.NewMapSeenSet())
+	rcache.put(newCheckpointRequest(t, "http://a.com/1"))
+	rcache.close()
+	// 模拟进程在写入一条记录时崩溃。
+	file, err := os.OpenFile(filepath.Join(dir, frontierFileName), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"op":"put","url":"http://a.com/2","meth`)
+	file.Close()
+
+	restored := openFileCache(t, dir, FRONTIER_FIFO, dedup.NewMapSeenSet())
+	defer restored.close()
+	if urls := drainUrls(restored); !reflect.DeepEqual(urls, []string{"http://a.com/1"}) {
+		t.Fatalf("restored = %v", urls)
+	}
+}
+
+func TestFileCacheReportsWriteErrors(t *testing.T) {
+	dir := newCheckpointDir(t)
+	defer os.RemoveAll(dir)
+	errs := make([]error, 0)
+	rcache, err := newFileRequestCache(dir, FRONTIER_FIFO, dedup.NewMapSeenSet(), func(err error) {
+		errs = append(errs, err)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 模拟磁盘写入失败。
+	rcache.file.Close()
+	rcache.put(newCheckpointRequest(t, "http://a.com/1"))
+	rcache.put(newCheckpointRequest(t, "http://a.com/2"))
+	if len(errs) != 1 {
+		t.Fatalf("errors = %v, want exactly one", errs)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	dir := newCheckpointDir(t)
+	defer os.RemoveAll(dir)
+	// 模拟中断的抓取：首页已处理完成，/a 还未处理。
+	rcache := openFileCache(t, dir, FRONTIER_FIFO, dedup.NewMapSeenSet())
+	rcache.put(newCheckpointRequest(t, "http://example.com/"))
+	rcache.done(rcache.get())
+	rcache.put(newCheckpointRequest(t, "http://example.com/a"))
+	rcache.close()
+
+	linkExtractor, err := analyzer.NewLinkExtractor(analyzer.LinkRules{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutex sync.Mutex
+	crawled := make([]string, 0)
+	collectUrl := func(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
+		mutex.Lock()
+		crawled = append(crawled, httpResp.Request.URL.String())
+		mutex.Unlock()
+		return nil, nil
+	}
+	seed, _ := http.NewRequest("GET", "http://example.com/", nil)
+	config := Config{
+		ChannelArgs:  base.NewChannelArgs(10, 10, 10, 10),
+		PoolBaseArgs: base.NewPoolBaseArgs(2, 2),
+		CrawlDepth:   3,
+		HttpClientGenerator: func() *http.Client {
+			transport, err := dl.NewReplayTransport(replayFixtureDir)
+			if err != nil {
+				panic(err)
+			}
+			return &http.Client{Transport: transport}
+		},
+		RespParsers: []analyzer.ParseResponse{linkExtractor.Parse, collectUrl},
+		ItemProcessors: []ipl.ProcessItem{func(item base.Item) (base.Item, error) {
+			return item, nil
+		}},
+		Seeds: []*http.Request{seed},
+	}
+	scheduler := NewScheduler()
+	if err := scheduler.Restore(context.Background(), dir, config); err != nil {
+		t.Fatal(err)
+	}
+	defer scheduler.Stop()
+
+	deadline := time.After(5 * time.Second)
+	for idleCount := 0; idleCount < 10; {
+		if scheduler.Idle() {
+			idleCount++
+		} else {
+			idleCount = 0
+		}
+		select {
+		case err := <-scheduler.ErrorChan():
+			t.Fatalf("Unexpected error: %s", err)
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("The restored crawl did not finish")
+		}
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	// 首页已完成，不会被再次抓取，因此也不会发现 /missing。
+	if want := []string{"http://example.com/a"}; !reflect.DeepEqual(crawled, want) {
+		t.Fatalf("crawled = %v, want %v", crawled, want)
+	}
+}
@@ -0,0 +1,256 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 17:58:31
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 17:58:31
+ */
+
+package scheduler
+
+import (
+	"container/heap"
+	"sort"
+	base "webcrawler/base"
+)
+
+// 请求缓存中请求的取出顺序。
+type FrontierOrder uint8
+
+const (
+	FRONTIER_FIFO             FrontierOrder = 0 // 先进先出（广度优先）。
+	FRONTIER_LIFO             FrontierOrder = 1 // 后进先出（深度优先）。
+	FRONTIER_PRIORITY         FrontierOrder = 2 // 优先级高的先出，相同时先进先出。
+	FRONTIER_HOST_ROUND_ROBIN FrontierOrder = 3 // 在各主机之间轮流取出。
+)
+
+var frontierOrderMap = map[FrontierOrder]string{
+	FRONTIER_FIFO:             "fifo",
+	FRONTIER_LIFO:             "lifo",
+	FRONTIER_PRIORITY:         "priority",
+	FRONTIER_HOST_ROUND_ROBIN: "host-round-robin",
+}
+
+func (order FrontierOrder) String() string {
+	if name, ok := frontierOrderMap[order]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// 请求的排序容器，非并发安全，由请求缓存负责加锁。
+type frontier interface {
+	push(req *base.Request)
+	pop() *base.Request
+	// 返回所有请求而不取出，把它们依次放入空的容器可以得到相同的取出顺序。
+	list() []*base.Request
+	len() int
+	capacity() int
+}
+
+func newFrontier(order FrontierOrder) frontier {
+	switch order {
+	case FRONTIER_LIFO:
+		return &lifoFrontier{}
+	case FRONTIER_PRIORITY:
+		return &priorityFrontier{}
+	case FRONTIER_HOST_ROUND_ROBIN:
+		return &hostFrontier{queues: make(map[string][]*base.Request)}
+	default:
+		return &fifoFrontier{}
+	}
+}
+
+type fifoFrontier struct {
+	reqs []*base.Request
+}
+
+func (f *fifoFrontier) push(req *base.Request) {
+	f.reqs = append(f.reqs, req)
+}
+
+func (f *fifoFrontier) pop() *base.Request {
+	if len(f.reqs) == 0 {
+		return nil
+	}
+	req := f.reqs[0]
+	f.reqs[0] = nil
+	f.reqs = f.reqs[1:]
+	return req
+}
+
+func (f *fifoFrontier) list() []*base.Request {
+	return append([]*base.Request(nil), f.reqs...)
+}
+
+func (f *fifoFrontier) len() int {
+	return len(f.reqs)
+}
+
+func (f *fifoFrontier) capacity() int {
+	return cap(f.reqs)
+}
+
+type lifoFrontier struct {
+	reqs []*base.Request
+}
+
+func (f *lifoFrontier) push(req *base.Request) {
+	f.reqs = append(f.reqs, req)
+}
+
+func (f *lifoFrontier) pop() *base.Request {
+	n := len(f.reqs)
+	if n == 0 {
+		return nil
+	}
+	req := f.reqs[n-1]
+	f.reqs[n-1] = nil
+	f.reqs = f.reqs[:n-1]
+	return req
+}
+
+func (f *lifoFrontier) list() []*base.Request {
+	return append([]*base.Request(nil), f.reqs...)
+}
+
+func (f *lifoFrontier) len() int {
+	return len(f.reqs)
+}
+
+func (f *lifoFrontier) capacity() int {
+	return cap(f.reqs)
+}
+
+type priorityItem struct {
+	req *base.Request
+	seq uint64
+}
+
+// 实现 heap.Interface。
+type priorityQueue []*priorityItem
+
+func (pq priorityQueue) Len() int {
+	return len(pq)
+}
+
+func (pq priorityQueue) Less(i, j int) bool {
+	pi, pj := pq[i].req.Priority(), pq[j].req.Priority()
+	if pi != pj {
+		return pi > pj
+	}
+	return pq[i].seq < pq[j].seq
+}
+
+func (pq priorityQueue) Swap(i, j int) {
+	pq[i], pq[j] = pq[j], pq[i]
+}
+
+func (pq *priorityQueue) Push(x interface{}) {
+	*pq = append(*pq, x.(*priorityItem))
+}
+
+func (pq *priorityQueue) Pop() interface{} {
+	old := *pq
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*pq = old[:n-1]
+	return item
+}
+
+type priorityFrontier struct {
+	queue priorityQueue
+	seq   uint64
+}
+
+func (f *priorityFrontier) push(req *base.Request) {
+	f.seq++
+	heap.Push(&f.queue, &priorityItem{req: req, seq: f.seq})
+}
+
+func (f *priorityFrontier) pop() *base.Request {
+	if len(f.queue) == 0 {
+		return nil
+	}
+	return heap.Pop(&f.queue).(*priorityItem).req
+}
+
+func (f *priorityFrontier) list() []*base.Request {
+	queue := append(priorityQueue(nil), f.queue...)
+	sort.Sort(queue)
+	reqs := make([]*base.Request, len(queue))
+	for i, item := range queue {
+		reqs[i] = item.req
+	}
+	return reqs
+}
+
+func (f *priorityFrontier) len() int {
+	return len(f.queue)
+}
+
+func (f *priorityFrontier) capacity() int {
+	return cap(f.queue)
+}
+
+type hostFrontier struct {
+	hosts  []string
+	queues map[string][]*base.Request
+	next   int
+	length int
+}
+
+func (f *hostFrontier) push(req *base.Request) {
+	host := getHostKey(req)
+	queue, ok := f.queues[host]
+	if !ok {
+		f.hosts = append(f.hosts, host)
+	}
+	f.queues[host] = append(queue, req)
+	f.length++
+}
+
+func (f *hostFrontier) pop() *base.Request {
+	if f.length == 0 {
+		return nil
+	}
+	if f.next >= len(f.hosts) {
+		f.next = 0
+	}
+	host := f.hosts[f.next]
+	queue := f.queues[host]
+	req := queue[0]
+	queue[0] = nil
+	queue = queue[1:]
+	f.length--
+	if len(queue) == 0 {
+		delete(f.queues, host)
+		f.hosts = append(f.hosts[:f.next], f.hosts[f.next+1:]...)
+	} else {
+		f.queues[host] = queue
+		f.next++
+	}
+	return req
+}
+
+// 按取出的顺序返回，重新放入时主机的轮流顺序不变。
+func (f *hostFrontier) list() []*base.Request {
+	reqs := make([]*base.Request, 0, f.length)
+	offsets := make(map[string]int, len(f.hosts))
+	for i := f.next; len(reqs) < f.length; i++ {
+		host := f.hosts[i%len(f.hosts)]
+		if offset := offsets[host]; offset < len(f.queues[host]) {
+			reqs = append(reqs, f.queues[host][offset])
+			offsets[host] = offset + 1
+		}
+	}
+	return reqs
+}
+
+func (f *hostFrontier) len() int {
+	return f.length
+}
+
+func (f *hostFrontier) capacity() int {
+	return f.length
+}
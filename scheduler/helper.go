@@ -10,13 +10,13 @@ package scheduler
 import (
 	"errors"
 	"fmt"
-	"regexp"
 	"strings"
 	anlz "webcrawler/analyzer"
 	base "webcrawler/base"
 	dl "webcrawler/downloader"
 	ipl "webcrawler/itempipeline"
 	mdw "webcrawler/middleware"
+	"webcrawler/publicsuffix"
 )
 
 func generateChannelManager(channelArgs base.ChannelArgs) mdw.ChannelManager {
@@ -36,13 +36,8 @@ func generateAnalyzerPool(total uint32) (anlz.AnalyzerPool, error) {
 	return analyerPool, nil
 }
 
-func generatePageDownloaderPool(total uint32, httpClientGenerator GenhttpClient) (dl.PageDownloaderPool, error) {
-	dlPool, err := dl.NewPageDownloaderPool(
-		total,
-		func() dl.PageDownloader {
-			return dl.NewPageDownloader(httpClientGenerator())
-		},
-	)
+func generatePageDownloaderPool(total uint32, gen dl.GenPageDownloader) (dl.PageDownloaderPool, error) {
+	dlPool, err := dl.NewPageDownloaderPool(total, gen)
 	if err != nil {
 		return nil, err
 	}
@@ -53,28 +48,6 @@ func generateItemProcessors(itemProcessors []ipl.ProcessItem) ipl.ItemPipeline {
 	return ipl.NewItemPipeline(itemProcessors)
 }
 
-var regexpForIp = regexp.MustCompile(`((?:(?:25[0-5]|2[0-4]\d|[01]?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|[01]?\d?\d))`)
-
-var regexpForDomain = []*regexp.Regexp{
-	regexp.MustCompile(`\.(com|com\.\w{2})$`),
-	regexp.MustCompile(`\.(gov|gov\.\w{2})$`),
-	regexp.MustCompile(`\.(net|net\.\w{2})$`),
-	regexp.MustCompile(`\.(org|org\.\w{2})$`),
-	// *.xx
-	regexp.MustCompile(`\.me$`),
-	regexp.MustCompile(`\.biz$`),
-	regexp.MustCompile(`\.info$`),
-	regexp.MustCompile(`\.name$`),
-	regexp.MustCompile(`\.mobi$`),
-	regexp.MustCompile(`\.so$`),
-	regexp.MustCompile(`\.asia$`),
-	regexp.MustCompile(`\.tel$`),
-	regexp.MustCompile(`\.tv$`),
-	regexp.MustCompile(`\.cc$`),
-	regexp.MustCompile(`\.co$`),
-	regexp.MustCompile(`\.\w{2}$`),
-}
-
 func generateCode(prefix string, id uint32) string {
 	return fmt.Sprintf("%s-%d", prefix, id)
 }
@@ -95,31 +68,11 @@ func parseCode(code string) []string {
 	return result
 }
 
+// 获取主机的主域名（可注册域名），支持带端口的主机和IP地址。
 func getPrimaryDomain(host string) (string, error) {
 	host = strings.TrimSpace(host)
 	if host == "" {
 		return "", errors.New("The host is empty!")
 	}
-	if regexpForIp.MatchString(host) {
-		return host, nil
-	}
-	var suffixMatch int
-	for _, reg := range regexpForDomain {
-		res := reg.FindStringIndex(host)
-		if len(res) > 0 {
-			suffixMatch = res[0]
-			break
-		}
-	}
-	if suffixMatch > 0 {
-		index := strings.LastIndex(host[:suffixMatch], ".")
-		if index > 0 {
-			index++
-		} else {
-			index = 0
-		}
-		return host[index:], nil
-	} else {
-		return "", errors.New("Unrecognized host!")
-	}
+	return publicsuffix.RegistrableDomain(host)
 }
@@ -0,0 +1,357 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 10:41:37
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 10:41:37
+ */
+
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	base "webcrawler/base"
+)
+
+// 等待被分发的请求的最大数量。
+const maxWaitingRequests = 1000
+
+// acquire 检查主机是否可以分发的间隔。
+const acquireInterval = 10 * time.Millisecond
+
+// 主机（或主域名）的状态闲置超过这个时间后会被清除，同时也是清除的间隔。
+// 主机限制器和自适应限速使用相同的清除策略。
+const hostStateIdle = time.Minute
+
+// 定期清除闲置状态的计时。
+type idleSweeper struct {
+	lastSweep time.Time
+}
+
+// 距上次清除是否已经过了清除的间隔，是则记为已清除。
+func (sweeper *idleSweeper) due(now time.Time) bool {
+	if sweeper.lastSweep.IsZero() {
+		sweeper.lastSweep = now
+	}
+	if now.Sub(sweeper.lastSweep) < hostStateIdle {
+		return false
+	}
+	sweeper.lastSweep = now
+	return true
+}
+
+// 最后使用时间为lastUsed的状态是否已经闲置到可以清除。
+func hostIdle(lastUsed time.Time, now time.Time) bool {
+	return now.Sub(lastUsed) >= hostStateIdle
+}
+
+// 按主机（及主域名）限制请求分发的速度和并发数。
+type hostLimiter interface {
+	// 放入一个等待分发的请求。
+	offer(req *base.Request)
+	// 取出一个已可以分发的请求，并将其记为进行中。
+	poll() *base.Request
+	// 在请求处理完成后调用。
+	release(req *base.Request)
+	// 不经过等待队列，直接等待主机的分发许可，用于下载robots.txt等不经过调度的请求。
+	acquire(ctx context.Context, host string) error
+	// 在 acquire 得到的请求完成后调用。
+	releaseHost(host string)
+	// 设置主机的最小抓取间隔，例如robots.txt中的Crawl-delay。
+	setHostDelay(host string, delay time.Duration)
+	// 设置自适应限速为主机决定的并发数和抓取间隔，并发数为0表示不限制。
+	setHostThrottle(host string, maxInFlight uint32, delay time.Duration)
+	waiting() int
+	summary() string
+}
+
+type hostState struct {
+	inFlight         uint32
+	lastDispatch     time.Time
+	delay            time.Duration
+	throttleInFlight uint32
+	throttleDelay    time.Duration
+	lastUsed         time.Time
+}
+
+type myHostLimiter struct {
+	args        base.HostLimitArgs
+	waitingReqs []*base.Request
+	hosts       map[string]*hostState
+	domains     map[string]*hostState
+	sweeper     idleSweeper
+	mutex       sync.Mutex
+}
+
+func newHostLimiter(args base.HostLimitArgs) hostLimiter {
+	return &myHostLimiter{
+		args:        args,
+		waitingReqs: make([]*base.Request, 0),
+		hosts:       make(map[string]*hostState),
+		domains:     make(map[string]*hostState),
+	}
+}
+
+func getHostKey(req *base.Request) string {
+	return strings.ToLower(req.HttpReq().URL.Host)
+}
+
+func getDomainKey(host string) string {
+	if pd, err := getPrimaryDomain(host); err == nil {
+		return pd
+	}
+	return host
+}
+
+func getState(states map[string]*hostState, key string) *hostState {
+	state, ok := states[key]
+	if !ok {
+		state = &hostState{}
+		states[key] = state
+	}
+	state.lastUsed = time.Now()
+	return state
+}
+
+func (limiter *myHostLimiter) offer(req *base.Request) {
+	if req == nil || !req.Valid() {
+		return
+	}
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	limiter.waitingReqs = append(limiter.waitingReqs, req)
+}
+
+func (limiter *myHostLimiter) poll() *base.Request {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	now := time.Now()
+	if limiter.sweeper.due(now) {
+		limiter.sweep(now)
+	}
+	blocked := make(map[string]bool)
+	for i, req := range limiter.waitingReqs {
+		host := getHostKey(req)
+		if blocked[host] {
+			continue
+		}
+		hs := getState(limiter.hosts, host)
+		ds := getState(limiter.domains, getDomainKey(host))
+		if !limiter.ready(hs, ds, now) {
+			blocked[host] = true
+			continue
+		}
+		hs.inFlight++
+		hs.lastDispatch = now
+		ds.inFlight++
+		ds.lastDispatch = now
+		limiter.waitingReqs = append(limiter.waitingReqs[:i], limiter.waitingReqs[i+1:]...)
+		return req
+	}
+	return nil
+}
+
+func (limiter *myHostLimiter) ready(hs *hostState, ds *hostState, now time.Time) bool {
+	max := limiter.args.MaxInFlightHost()
+	if hs.throttleInFlight > 0 && (max == 0 || hs.throttleInFlight < max) {
+		max = hs.throttleInFlight
+	}
+	if max > 0 && hs.inFlight >= max {
+		return false
+	}
+	if max := limiter.args.MaxInFlightDomain(); max > 0 && ds.inFlight >= max {
+		return false
+	}
+	delay := limiter.args.CrawlDelay()
+	if delay > 0 && now.Sub(ds.lastDispatch) < delay {
+		return false
+	}
+	if hs.delay > delay {
+		delay = hs.delay
+	}
+	if hs.throttleDelay > delay {
+		delay = hs.throttleDelay
+	}
+	if delay > 0 && now.Sub(hs.lastDispatch) < delay {
+		return false
+	}
+	return true
+}
+
+// 清除闲置的主机和主域名的状态，需要在持有锁的情况下调用。
+// 只清除没有进行中和等待中的请求、抓取间隔已经过去、并且没有自适应限速设置的状态。
+// 自适应限速的设置由它在清除自己的闲置状态时解除；robots.txt的抓取间隔在每个请求放入前都会重新设置。
+func (limiter *myHostLimiter) sweep(now time.Time) {
+	waitingHosts := make(map[string]bool)
+	waitingDomains := make(map[string]bool)
+	for _, req := range limiter.waitingReqs {
+		host := getHostKey(req)
+		waitingHosts[host] = true
+		waitingDomains[getDomainKey(host)] = true
+	}
+	limiter.sweepStates(limiter.hosts, waitingHosts, now)
+	limiter.sweepStates(limiter.domains, waitingDomains, now)
+}
+
+func (limiter *myHostLimiter) sweepStates(states map[string]*hostState, waiting map[string]bool, now time.Time) {
+	for key, state := range states {
+		if state.inFlight > 0 || waiting[key] || !hostIdle(state.lastUsed, now) {
+			continue
+		}
+		if state.throttleInFlight > 0 || state.throttleDelay > 0 {
+			continue
+		}
+		delay := limiter.args.CrawlDelay()
+		if state.delay > delay {
+			delay = state.delay
+		}
+		if now.Sub(state.lastDispatch) < delay {
+			continue
+		}
+		delete(states, key)
+	}
+}
+
+func (limiter *myHostLimiter) release(req *base.Request) {
+	if req == nil || !req.Valid() {
+		return
+	}
+	limiter.releaseHost(getHostKey(req))
+}
+
+func (limiter *myHostLimiter) acquire(ctx context.Context, host string) error {
+	host = strings.ToLower(host)
+	for {
+		limiter.mutex.Lock()
+		now := time.Now()
+		hs := getState(limiter.hosts, host)
+		ds := getState(limiter.domains, getDomainKey(host))
+		if limiter.ready(hs, ds, now) {
+			hs.inFlight++
+			hs.lastDispatch = now
+			ds.inFlight++
+			ds.lastDispatch = now
+			limiter.mutex.Unlock()
+			return nil
+		}
+		limiter.mutex.Unlock()
+		timer := time.NewTimer(acquireInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (limiter *myHostLimiter) releaseHost(host string) {
+	host = strings.ToLower(host)
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if hs, ok := limiter.hosts[host]; ok && hs.inFlight > 0 {
+		hs.inFlight--
+	}
+	if ds, ok := limiter.domains[getDomainKey(host)]; ok && ds.inFlight > 0 {
+		ds.inFlight--
+	}
+}
+
+func (limiter *myHostLimiter) setHostDelay(host string, delay time.Duration) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	getState(limiter.hosts, strings.ToLower(host)).delay = delay
+}
+
+func (limiter *myHostLimiter) setHostThrottle(host string, maxInFlight uint32, delay time.Duration) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	hs := getState(limiter.hosts, strings.ToLower(host))
+	hs.throttleInFlight = maxInFlight
+	hs.throttleDelay = delay
+}
+
+func (limiter *myHostLimiter) waiting() int {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	return len(limiter.waitingReqs)
+}
+
+var hostLimiterSummaryTemplate = "args: %s, waiting: %d, throttled hosts: %s"
+
+func (limiter *myHostLimiter) summary() string {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	waitingCount := make(map[string]int)
+	for _, req := range limiter.waitingReqs {
+		waitingCount[getHostKey(req)]++
+	}
+	hosts := make([]string, 0)
+	for host := range waitingCount {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	var buffer bytes.Buffer
+	buffer.WriteByte('[')
+	for i, host := range hosts {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		// 直接读取状态，避免创建新的状态或更新最后使用时间。
+		var inFlight uint32
+		if hs, ok := limiter.hosts[host]; ok {
+			inFlight = hs.inFlight
+		}
+		buffer.WriteString(fmt.Sprintf("%s(inFlight: %d, waiting: %d)",
+			host, inFlight, waitingCount[host]))
+	}
+	buffer.WriteByte(']')
+	return fmt.Sprintf(hostLimiterSummaryTemplate, limiter.args.String(), len(limiter.waitingReqs), buffer.String())
+}
+
+// 受主机限制器约束的Transport，请求在响应体关闭（或请求失败）后才算完成。
+type hostLimitedTransport struct {
+	transport http.RoundTripper
+	limiter   hostLimiter
+}
+
+func newHostLimitedTransport(transport http.RoundTripper, limiter hostLimiter) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	return &hostLimitedTransport{transport: transport, limiter: limiter}
+}
+
+func (t *hostLimitedTransport) RoundTrip(httpReq *http.Request) (*http.Response, error) {
+	host := httpReq.URL.Host
+	if err := t.limiter.acquire(httpReq.Context(), host); err != nil {
+		return nil, err
+	}
+	httpResp, err := t.transport.RoundTrip(httpReq)
+	if err != nil {
+		t.limiter.releaseHost(host)
+		return nil, err
+	}
+	httpResp.Body = &releasingBody{ReadCloser: httpResp.Body, release: func() { t.limiter.releaseHost(host) }}
+	return httpResp, nil
+}
+
+// 关闭时释放主机许可的响应体。
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (body *releasingBody) Close() error {
+	err := body.ReadCloser.Close()
+	body.once.Do(body.release)
+	return err
+}
@@ -0,0 +1,95 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"webcrawler/base"
+)
+
+func newLimiterTestRequest(t *testing.T, rawUrl string) *base.Request {
+	httpReq, err := http.NewRequest("GET", rawUrl, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base.NewRequest(httpReq, 0)
+}
+
+func TestHostLimiterSweepsIdleStates(t *testing.T) {
+	limiter := newHostLimiter(base.NewHostLimitArgs(0, 1, 0)).(*myHostLimiter)
+	done := newLimiterTestRequest(t, "http://a.example.com/")
+	busy := newLimiterTestRequest(t, "http://b.example.org/")
+	waiting := newLimiterTestRequest(t, "http://b.example.org/next")
+	limiter.setHostDelay("c.example.net", 2*hostStateIdle)
+	limiter.setHostThrottle("d.example.net", 2, time.Second)
+	limiter.offer(done)
+	limiter.offer(busy)
+	limiter.offer(waiting)
+	if limiter.poll() != done || limiter.poll() != busy || limiter.poll() != nil {
+		t.Fatalf("Unexpected poll order")
+	}
+	limiter.release(done)
+	limiter.hosts["c.example.net"].lastDispatch = time.Now()
+
+	limiter.mutex.Lock()
+	limiter.sweep(time.Now().Add(hostStateIdle))
+	limiter.mutex.Unlock()
+	if _, ok := limiter.hosts["a.example.com"]; ok {
+		t.Fatalf("The idle host state is not swept")
+	}
+	if _, ok := limiter.domains["example.com"]; ok {
+		t.Fatalf("The idle domain state is not swept")
+	}
+	if _, ok := limiter.hosts["b.example.org"]; !ok {
+		t.Fatalf("The host state with requests in flight is swept")
+	}
+	if _, ok := limiter.hosts["c.example.net"]; !ok {
+		t.Fatalf("The host state within its crawl delay is swept")
+	}
+	if _, ok := limiter.hosts["d.example.net"]; !ok {
+		t.Fatalf("The throttled host state is swept")
+	}
+	limiter.release(busy)
+	if limiter.poll() != waiting {
+		t.Fatalf("The waiting request is not dispatched after release")
+	}
+}
+
+func TestHostLimiterSummaryKeepsStates(t *testing.T) {
+	limiter := newHostLimiter(base.NewHostLimitArgs(0, 0, 0)).(*myHostLimiter)
+	limiter.offer(newLimiterTestRequest(t, "http://a.example.com/"))
+	limiter.summary()
+	if len(limiter.hosts) != 0 {
+		t.Fatalf("summary() created host states: %v", limiter.hosts)
+	}
+}
+
+func TestHostLimitedTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("User-agent: *\n"))
+	}))
+	defer server.Close()
+	limiter := newHostLimiter(base.NewHostLimitArgs(0, 1, 0)).(*myHostLimiter)
+	client := &http.Client{Transport: newHostLimitedTransport(nil, limiter)}
+	httpResp, err := client.Get(server.URL + "/robots.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 响应体关闭之前主机的许可一直被占用。
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := limiter.acquire(ctx, httpResp.Request.URL.Host); err == nil {
+		t.Fatalf("acquire() should wait for the open response")
+	}
+	httpResp.Body.Close()
+	httpResp.Body.Close()
+	if hs := limiter.hosts[strings.ToLower(httpResp.Request.URL.Host)]; hs.inFlight != 0 {
+		t.Fatalf("inFlight = %d after the response is closed", hs.inFlight)
+	}
+	if err := limiter.acquire(context.Background(), httpResp.Request.URL.Host); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,85 @@
+package scheduler
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"webcrawler/analyzer"
+	"webcrawler/base"
+	dl "webcrawler/downloader"
+	ipl "webcrawler/itempipeline"
+)
+
+// 夹具目录中只有首页和 /a 两个页面，首页中指向 /missing 的链接没有对应的夹具。
+const replayFixtureDir = "testdata/replay"
+
+func TestReplayCrawl(t *testing.T) {
+	linkExtractor, err := analyzer.NewLinkExtractor(analyzer.LinkRules{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var mutex sync.Mutex
+	crawled := make([]string, 0)
+	collectUrl := func(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
+		mutex.Lock()
+		crawled = append(crawled, httpResp.Request.URL.String())
+		mutex.Unlock()
+		return nil, nil
+	}
+	seed, _ := http.NewRequest("GET", "http://example.com/", nil)
+	config := Config{
+		ChannelArgs:  base.NewChannelArgs(10, 10, 10, 10),
+		PoolBaseArgs: base.NewPoolBaseArgs(2, 2),
+		CrawlDepth:   3,
+		HttpClientGenerator: func() *http.Client {
+			transport, err := dl.NewReplayTransport(replayFixtureDir)
+			if err != nil {
+				panic(err)
+			}
+			return &http.Client{Transport: transport}
+		},
+		RespParsers: []analyzer.ParseResponse{linkExtractor.Parse, collectUrl},
+		ItemProcessors: []ipl.ProcessItem{func(item base.Item) (base.Item, error) {
+			return item, nil
+		}},
+		Seeds: []*http.Request{seed},
+	}
+	scheduler := NewScheduler()
+	if err := scheduler.Start(context.Background(), config); err != nil {
+		t.Fatal(err)
+	}
+	defer scheduler.Stop()
+
+	// 没有夹具的请求应该以明确的错误失败，而不是访问网络。
+	var missErr error
+	deadline := time.After(5 * time.Second)
+	// 通道中的请求和响应不计入Idle，因此需要连续多次空闲。
+	for idleCount := 0; missErr == nil || idleCount < 10; {
+		if scheduler.Idle() {
+			idleCount++
+		} else {
+			idleCount = 0
+		}
+		select {
+		case err := <-scheduler.ErrorChan():
+			if strings.Contains(err.Error(), "No fixture for GET http://example.com/missing") {
+				missErr = err
+			}
+		case <-time.After(10 * time.Millisecond):
+		case <-deadline:
+			t.Fatalf("The replay crawl did not finish (missErr=%v)", missErr)
+		}
+	}
+	mutex.Lock()
+	defer mutex.Unlock()
+	sort.Strings(crawled)
+	want := []string{"http://example.com/", "http://example.com/a"}
+	if !reflect.DeepEqual(crawled, want) {
+		t.Fatalf("crawled = %v, want %v", crawled, want)
+	}
+}
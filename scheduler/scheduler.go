@@ -8,19 +8,24 @@
 package scheduler
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"logging"
 	"net/http"
 	// "strings"
+	"sync"
 	"sync/atomic"
 	"time"
 	"webcrawler/analyzer"
 	anlz "webcrawler/analyzer"
 	base "webcrawler/base"
+	"webcrawler/dedup"
 	dl "webcrawler/downloader"
 	ipl "webcrawler/itempipeline"
 	mdw "webcrawler/middleware"
+	"webcrawler/robots"
+	"webcrawler/warc"
 )
 
 const (
@@ -28,22 +33,35 @@ const (
 	ANALYZER_CODE     = "analyzer"
 	ITEMPIPELINE_CODE = "item_pipeline"
 	SCHEDULER_CODE    = "scheduler"
+	ROBOTS_CODE       = "robots"
 )
 
 var logger logging.Logger = logging.NewSimpleLogger()
 
+// 硬停止时等待各个goroutine退出的最长时间。
+const stopTimeout = 5 * time.Second
+
 type GenhttpClient func() *http.Client
 
 type Scheduler interface {
-	Start(channelArgs base.ChannelArgs,
-		poolBaseArgs base.PoolBaseArgs,
-		crawlDepth uint32,
-		httpClientGenerator GenhttpClient,
-		respParsers []analyzer.ParseResponse,
-		itemProcessors []ipl.ProcessItem,
-		firstHttpReq *http.Request) (err error)
+	// 开始抓取。ctx被取消时调度器会立即停止，与调用Stop的效果相同。
+	Start(ctx context.Context, config Config) (err error)
+	// 从检查点目录中恢复并继续抓取，该目录不存在时会从第一个请求开始抓取。
+	Restore(ctx context.Context, checkpointDir string, config Config) (err error)
+	// 立即停止：取消所有正在进行的下载和分析，并等待各个goroutine退出。
 	Stop() bool
+	// 平滑停止：不再接受新的URL，等待正在进行的下载、分析和条目处理完成之后再停止。
+	// timeout大于0时，超时之后会转为立即停止并返回错误。
+	Drain(timeout time.Duration) error
+	// 暂停：不再从请求缓存中分发请求，正在进行的下载和分析不受影响。
+	Pause() bool
+	// 从暂停中恢复。
+	Resume() bool
+	// 在运行时调整网页下载器池和分析器池的大小。
+	// 缩小时正在使用的下载器和分析器会在完成当前的工作之后被移除。
+	SetPoolSizes(poolBaseArgs base.PoolBaseArgs) error
 	Running() bool
+	Paused() bool
 	ErrorChan() <-chan error
 	Idle() bool
 	Summary(prefix string) SchedSummary
@@ -52,8 +70,15 @@ type Scheduler interface {
 type myScheduler struct {
 	channelArgs   base.ChannelArgs
 	poolBaseArgs  base.PoolBaseArgs
+	hostLimitArgs base.HostLimitArgs
+	robotsArgs    base.RobotsArgs
+	retryPolicy   dl.RetryPolicy
+	rateLimiter   dl.RateLimiter
+	httpCache     dl.HttpCache
+	skipUnchanged bool
+	warcWriter    warc.Writer
 	crawlDepth    uint32
-	primaryDomain string
+	scope         crawlScope
 	chanman       mdw.ChannelManager
 	stopSign      mdw.StopSign
 	dlpool        dl.PageDownloaderPool
@@ -61,21 +86,41 @@ type myScheduler struct {
 	itemPipeline  ipl.ItemPipeline
 	running       uint32
 	reqCache      requestCache
-	urlMap        map[string]bool
+	hostLimiter   hostLimiter
+	autoThrottle  autoThrottle
+	robotsCache   robots.RobotsCache
+	canonicalizer base.Canonicalizer
+	seenSet       dedup.SeenSet
+	analyzing     sync.Map // 正在分析的响应（*http.Response）对应的请求，分析完成之后才标记为完成。
+	retrying      int32    // 正在等待重试的请求数。
+	retried       uint64   // 已重试的总次数。
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup // 所有会向通道发送数据的goroutine。
+	stopped       chan struct{}  // 停止完成之后被关闭。
+	inFlight      int64          // 已发出但还未处理完的请求、响应和条目的数量。
+	draining      uint32         // 平滑停止的标记。
+	paused        uint32         // 暂停的标记。
+	poolMutex     sync.Mutex     // 保护poolBaseArgs。
 }
 
 func NewScheduler() Scheduler {
 	return &myScheduler{}
 }
 
-func (sched *myScheduler) Start(channelArgs base.ChannelArgs,
-	poolBaseArgs base.PoolBaseArgs,
-	crawlDepth uint32,
-	httpClientGenerator GenhttpClient,
-	respParsers []analyzer.ParseResponse,
-	itemProcessors []ipl.ProcessItem,
-	firstHttpReq *http.Request) (err error) {
+func (sched *myScheduler) Start(ctx context.Context, config Config) (err error) {
+	return sched.start(ctx, config)
+}
+
+func (sched *myScheduler) Restore(ctx context.Context, checkpointDir string, config Config) (err error) {
+	if checkpointDir == "" {
+		return errors.New("The checkpoint directory is invalid!\n")
+	}
+	config.CheckpointDir = checkpointDir
+	return sched.start(ctx, config)
+}
 
+func (sched *myScheduler) start(ctx context.Context, config Config) (err error) {
 	defer func() {
 		if p := recover(); p != nil {
 			errMsg := fmt.Sprintf("Fatal Scheduler Error: %s\n", p)
@@ -87,23 +132,60 @@ func (sched *myScheduler) Start(channelArgs base.ChannelArgs,
 		return errors.New("The scheduler has been started!\n")
 	}
 
-	if err := channelArgs.Check(); err != nil {
+	if ctx == nil {
+		return errors.New("The context is invalid!\n")
+	}
+	if err := config.Check(); err != nil {
 		return err
 	}
-	sched.channelArgs = channelArgs
-
-	if err := poolBaseArgs.Check(); err != nil {
+	scope, err := newCrawlScope(config.Scope, config.Seeds)
+	if err != nil {
 		return err
 	}
-	sched.poolBaseArgs = poolBaseArgs
-	sched.crawlDepth = crawlDepth
+	sched.scope = scope
+	sched.channelArgs = config.ChannelArgs
+	sched.poolBaseArgs = config.PoolBaseArgs
+	sched.hostLimitArgs = config.HostLimitArgs
+	sched.robotsArgs = config.RobotsArgs
+	sched.retryPolicy = config.RetryPolicy
+	sched.rateLimiter = config.RateLimiter
+	sched.httpCache = config.HttpCache
+	sched.skipUnchanged = config.SkipUnchanged
+	sched.warcWriter = config.WarcWriter
+	sched.crawlDepth = config.CrawlDepth
+	httpClientGenerator := config.HttpClientGenerator
+	if config.Canonicalizer != nil {
+		sched.canonicalizer = config.Canonicalizer
+	} else {
+		sched.canonicalizer = base.NewCanonicalizer(base.DefaultCanonicalOptions())
+	}
 
 	sched.chanman = generateChannelManager(sched.channelArgs)
-	if httpClientGenerator == nil {
-		return errors.New("The Http Client generator list is invalid!\n")
-	}
 
-	dlpool, err := generatePageDownloaderPool(sched.poolBaseArgs.PageDownloaderPoolSize(), httpClientGenerator)
+	pageDownloaderGenerator := config.PageDownloaderGenerator
+	if pageDownloaderGenerator == nil {
+		options := dl.Options{
+			RetryPolicy: sched.retryPolicy,
+			RateLimiter: sched.rateLimiter,
+			HttpCache:   sched.httpCache,
+			WarcWriter:  sched.warcWriter,
+			MaxBodySize: config.MaxBodySize,
+		}
+		pageDownloaderGenerator = func() dl.PageDownloader {
+			return dl.NewPageDownloaderWithOptions(httpClientGenerator(), options)
+		}
+	}
+	if middlewares := config.DownloaderMiddlewares; len(middlewares) > 0 {
+		innerGenerator := pageDownloaderGenerator
+		pageDownloaderGenerator = func() dl.PageDownloader {
+			downloader, err := dl.NewMiddlewarePageDownloader(innerGenerator(), middlewares...)
+			if err != nil {
+				panic(err)
+			}
+			return downloader
+		}
+	}
+	dlpool, err := generatePageDownloaderPool(sched.poolBaseArgs.PageDownloaderPoolSize(), pageDownloaderGenerator)
 	if err != nil {
 		errMsg := fmt.Sprintf("Occur error when get page downloader pool: %s\n", err)
 		return errors.New(errMsg)
@@ -117,15 +199,7 @@ func (sched *myScheduler) Start(channelArgs base.ChannelArgs,
 	}
 	sched.analyzerPool = analyzerPool
 
-	if itemProcessors == nil {
-		return errors.New("The item processor list is invalid!\n")
-	}
-	for i, item := range itemProcessors {
-		if item == nil {
-			return errors.New(fmt.Sprintf("The %dth item processor is invalid!\n", i))
-		}
-	}
-	sched.itemPipeline = generateItemProcessors(itemProcessors)
+	sched.itemPipeline = generateItemProcessors(config.ItemProcessors)
 
 	if sched.stopSign == nil {
 		sched.stopSign = mdw.NewStopSign()
@@ -133,47 +207,122 @@ func (sched *myScheduler) Start(channelArgs base.ChannelArgs,
 		sched.stopSign.Reset()
 	}
 
-	sched.reqCache = newRequestCache()
-	sched.urlMap = make(map[string]bool)
+	if config.SeenSet != nil {
+		sched.seenSet = config.SeenSet
+	} else {
+		sched.seenSet = dedup.NewMapSeenSet()
+	}
+	if config.CheckpointDir == "" {
+		sched.reqCache = newRequestCache(config.FrontierOrder)
+	} else {
+		fileCache, err := newFileRequestCache(config.CheckpointDir, config.FrontierOrder, sched.seenSet,
+			func(err error) { sched.sendError(err, SCHEDULER_CODE) })
+		if err != nil {
+			errMsg := fmt.Sprintf("Occur error when load checkpoint '%s': %s\n", config.CheckpointDir, err)
+			return errors.New(errMsg)
+		}
+		sched.reqCache = fileCache
+	}
+	sched.hostLimiter = newHostLimiter(sched.hostLimitArgs)
+	if config.AutoThrottleArgs.TargetLatency() != 0 {
+		sched.autoThrottle = newAutoThrottle(config.AutoThrottleArgs, sched.hostLimiter)
+	} else {
+		sched.autoThrottle = nil
+	}
+	if sched.robotsArgs.UserAgent() != "" {
+		robotsClient := &http.Client{}
+		if httpClientGenerator != nil {
+			robotsClient = httpClientGenerator()
+		}
+		// 下载robots.txt同样受主机限制器和速率限制的约束。
+		limitedClient := *robotsClient
+		limitedClient.Transport = newHostLimitedTransport(robotsClient.Transport, sched.hostLimiter)
+		if sched.rateLimiter != nil {
+			limitedClient.Transport = dl.NewRateLimitedTransport(limitedClient.Transport, sched.rateLimiter)
+		}
+		robotsClient = &limitedClient
+		sched.robotsCache = robots.NewRobotsCache(robotsClient, sched.robotsArgs.UserAgent(), sched.robotsArgs.CacheTTL())
+	} else {
+		sched.robotsCache = nil
+	}
+
+	sched.ctx, sched.cancel = context.WithCancel(ctx)
+	sched.stopped = make(chan struct{})
+	atomic.StoreInt64(&sched.inFlight, 0)
+	atomic.StoreUint32(&sched.draining, 0)
+	atomic.StoreUint32(&sched.paused, 0)
 
 	sched.startDownloading()
-	sched.activateAnalyzers(respParsers)
+	sched.activateAnalyzers(config.RespParsers)
 	sched.openItemPipeline()
 	sched.schedule(100 * time.Millisecond)
+	sched.watch()
 
-	if firstHttpReq == nil {
-		return errors.New("The first Http request is invalid!\n")
-	}
-	pd, err := getPrimaryDomain(firstHttpReq.Host)
-	if err != nil {
-		return err
+	for _, seed := range config.Seeds {
+		seedReq := base.NewRequest(seed, 0)
+		if !sched.allowedByRobots(seedReq) {
+			continue
+		}
+		canonicalUrl := sched.canonicalizer.Canonicalize(seed.URL)
+		seedReq.SetCanonicalUrl(canonicalUrl)
+		added, err := sched.seenSet.Add(canonicalUrl)
+		if err != nil {
+			sched.sendError(err, SCHEDULER_CODE)
+			continue
+		}
+		if added {
+			sched.reqCache.put(seedReq)
+		}
 	}
-	sched.primaryDomain = pd
-	firstReq := base.NewRequest(firstHttpReq, 0)
-	sched.reqCache.put(firstReq)
 	atomic.StoreUint32(&sched.running, 1)
 	return nil
 }
 
 func (sched *myScheduler) schedule(interval time.Duration) {
+	sched.wg.Add(1)
 	go func() {
+		defer sched.wg.Done()
 		for {
 			if sched.stopSign.Signed() {
 				sched.stopSign.Deal(SCHEDULER_CODE)
 				return
 			}
+			select {
+			case <-sched.ctx.Done():
+				return
+			default:
+			}
+			if atomic.LoadUint32(&sched.draining) == 1 || atomic.LoadUint32(&sched.paused) == 1 {
+				time.Sleep(interval)
+				continue
+			}
 			remainder := cap(sched.getReqChan()) - len(sched.getReqChan())
 			var temp *base.Request
 			for remainder > 0 {
-				temp = sched.reqCache.get()
+				temp = sched.hostLimiter.poll()
 				if temp == nil {
-					break
+					// 只有在等待的请求都不能分发时才从请求缓存中取出新的请求，以保持其顺序。
+					if sched.hostLimiter.waiting() >= maxWaitingRequests {
+						break
+					}
+					next := sched.reqCache.get()
+					if next == nil {
+						break
+					}
+					sched.hostLimiter.offer(next)
+					continue
 				}
 				if sched.stopSign.Signed() {
 					sched.stopSign.Deal(SCHEDULER_CODE)
 					return
 				}
-				sched.getReqChan() <- *temp
+				atomic.AddInt64(&sched.inFlight, 1)
+				select {
+				case sched.getReqChan() <- *temp:
+				case <-sched.ctx.Done():
+					atomic.AddInt64(&sched.inFlight, -1)
+					return
+				}
 				remainder--
 			}
 			time.Sleep(interval)
@@ -182,11 +331,23 @@ func (sched *myScheduler) schedule(interval time.Duration) {
 }
 
 func (sched *myScheduler) openItemPipeline() {
+	itemChan := sched.getItemChan()
+	sched.wg.Add(1)
 	go func() {
+		defer sched.wg.Done()
 		sched.itemPipeline.SetFailFast(false)
 		code := ITEMPIPELINE_CODE
-		for item := range sched.getItemChan() {
+		for {
+			var item base.Item
+			select {
+			case item = <-itemChan:
+			case <-sched.ctx.Done():
+				return
+			}
+			sched.wg.Add(1)
 			go func(item base.Item) {
+				defer sched.wg.Done()
+				defer atomic.AddInt64(&sched.inFlight, -1)
 				defer func() {
 					if p := recover(); p != nil {
 						errMsg := fmt.Sprintf("Fatal Item Processing Error:%s", p)
@@ -205,28 +366,44 @@ func (sched *myScheduler) openItemPipeline() {
 }
 
 func (sched *myScheduler) activateAnalyzers(respParsers []analyzer.ParseResponse) {
+	respChan := sched.getRespChan()
+	sched.wg.Add(1)
 	go func() {
+		defer sched.wg.Done()
 		for {
-			resp, ok := <-sched.getRespChan()
-			if !ok {
-				break
+			var resp base.Response
+			select {
+			case resp = <-respChan:
+			case <-sched.ctx.Done():
+				return
 			}
+			sched.wg.Add(1)
 			go sched.analyze(respParsers, resp)
 		}
 	}()
 }
 
 func (sched *myScheduler) analyze(respParsers []analyzer.ParseResponse, resp base.Response) {
+	defer sched.wg.Done()
+	defer atomic.AddInt64(&sched.inFlight, -1)
 	defer func() {
 		if p := recover(); p != nil {
 			errMsg := fmt.Sprintf("Fatal Analysis Error: %s\n", p)
 			logger.Fatal(errMsg)
 		}
 	}()
+	if resp.Unchanged() && sched.skipUnchanged {
+		logger.Infof("Skip the unchanged response. (requestUrl=%s)\n", resp.HttpResp().Request.URL)
+		resp.HttpResp().Body.Close()
+		sched.analyzed(resp)
+		return
+	}
 	analyzer, err := sched.analyzerPool.Take()
 	if err != nil {
 		errMsg := fmt.Sprintf("Analyzer pool error:%s\n", err)
 		sched.sendError(errors.New(errMsg), SCHEDULER_CODE)
+		resp.HttpResp().Body.Close()
+		return
 	}
 	defer func() {
 		err := sched.analyzerPool.Return(analyzer)
@@ -259,9 +436,24 @@ func (sched *myScheduler) analyze(respParsers []analyzer.ParseResponse, resp bas
 			sched.sendError(err, code)
 		}
 	}
+	sched.analyzed(resp)
 	// os.Exit(0)
 }
 
+// 在响应分析完成（其中的请求都已被保存）之后把对应的请求标记为完成。
+// 停止过程中的请求不被标记，因为其中的请求可能已被忽略，恢复时会重新下载。
+func (sched *myScheduler) analyzed(resp base.Response) {
+	req, ok := sched.analyzing.Load(resp.HttpResp())
+	if !ok {
+		return
+	}
+	sched.analyzing.Delete(resp.HttpResp())
+	if sched.ctx.Err() != nil || sched.stopSign.Signed() || atomic.LoadUint32(&sched.draining) == 1 {
+		return
+	}
+	sched.reqCache.done(req.(*base.Request))
+}
+
 func (sched *myScheduler) saveReqToCache(req base.Request, code string) bool {
 	httpReq := req.HttpReq()
 	if httpReq == nil {
@@ -280,13 +472,20 @@ func (sched *myScheduler) saveReqToCache(req base.Request, code string) bool {
 	// 	return false
 	// }
 
-	if _, ok := sched.urlMap[reqUrl.String()]; ok {
+	canonicalUrl := sched.canonicalizer.Canonicalize(reqUrl)
+	req.SetCanonicalUrl(canonicalUrl)
+	seen, err := sched.seenSet.Contains(canonicalUrl)
+	if err != nil {
+		sched.sendError(err, code)
+		return false
+	}
+	if seen {
 		logger.Warnf("Ignore the request! It's url is repeated. (requestUrl=%s)\n", reqUrl)
 		return false
 	}
 
-	if pd, _ := getPrimaryDomain(reqUrl.Host); pd != sched.primaryDomain {
-		logger.Warnf("Ignore the request! It's host '%s' not in primary domain '%s' . (reuqestUrl=%s)\n", httpReq.Host, sched.primaryDomain, reqUrl)
+	if err := sched.scope.check(reqUrl); err != nil {
+		logger.Warnf("Ignore the request! %s. (requestUrl=%s)\n", err, reqUrl)
 		return false
 	}
 
@@ -295,22 +494,74 @@ func (sched *myScheduler) saveReqToCache(req base.Request, code string) bool {
 		return false
 	}
 
+	if !sched.allowedByRobots(&req) {
+		return false
+	}
+
 	if sched.stopSign.Signed() {
 		sched.stopSign.Deal(code)
+		return false
+	}
+	if atomic.LoadUint32(&sched.draining) == 1 {
+		logger.Warnf("Ignore the request! The scheduler is draining. (requestUrl=%s)\n", reqUrl)
+		return false
+	}
+	added, err := sched.seenSet.Add(canonicalUrl)
+	if err != nil {
+		sched.sendError(err, code)
+		return false
+	}
+	if !added {
+		logger.Warnf("Ignore the request! It's url is repeated. (requestUrl=%s)\n", reqUrl)
+		return false
 	}
 	sched.reqCache.put(&req)
-	sched.urlMap[reqUrl.String()] = true
 	return true
 
 }
 
+// 检查请求是否被robots.txt允许，并为其设置User-Agent。
+func (sched *myScheduler) allowedByRobots(req *base.Request) bool {
+	if sched.robotsCache == nil {
+		return true
+	}
+	httpReq := req.HttpReq()
+	reqUrl := httpReq.URL
+	rbs, err := sched.robotsCache.Get(sched.ctx, reqUrl)
+	if err != nil {
+		// 下载robots.txt失败时丢弃请求，并报告真正的原因。
+		errMsg := fmt.Sprintf("Ignore the request! Its robots.txt is unavailable. (requestUrl=%s): %s", reqUrl, err)
+		sched.sendError(errors.New(errMsg), ROBOTS_CODE)
+		return false
+	}
+	if rbs == nil {
+		return false
+	}
+	sched.hostLimiter.setHostDelay(reqUrl.Host, rbs.CrawlDelay())
+	if !rbs.Allowed(robots.RequestPath(reqUrl)) {
+		errMsg := fmt.Sprintf("Ignore the request! It's disallowed by robots.txt for user-agent '%s'. (requestUrl=%s)\n", sched.robotsCache.UserAgent(), reqUrl)
+		sched.sendError(errors.New(errMsg), ROBOTS_CODE)
+		return false
+	}
+	if httpReq.Header.Get("User-Agent") == "" {
+		httpReq.Header.Set("User-Agent", sched.robotsCache.UserAgent())
+	}
+	return true
+}
+
 func (sched *myScheduler) sendItem(item base.Item, code string) bool {
 	if sched.stopSign.Signed() {
 		sched.stopSign.Deal(code)
 		return false
 	}
-	sched.getItemChan() <- item
-	return true
+	atomic.AddInt64(&sched.inFlight, 1)
+	select {
+	case sched.getItemChan() <- item:
+		return true
+	case <-sched.ctx.Done():
+		atomic.AddInt64(&sched.inFlight, -1)
+		return false
+	}
 }
 
 func (sched *myScheduler) getItemChan() chan base.Item {
@@ -322,13 +573,18 @@ func (sched *myScheduler) getItemChan() chan base.Item {
 }
 
 func (sched *myScheduler) startDownloading() {
+	reqChan := sched.getReqChan()
+	sched.wg.Add(1)
 	go func() {
+		defer sched.wg.Done()
 		for {
-			req, ok := <-sched.getReqChan()
-			if !ok {
-				break
+			var req base.Request
+			select {
+			case req = <-reqChan:
+			case <-sched.ctx.Done():
+				return
 			}
-
+			sched.wg.Add(1)
 			go sched.download(req)
 		}
 	}()
@@ -351,10 +607,14 @@ func (sched *myScheduler) getErrChan() chan error {
 }
 
 func (sched *myScheduler) download(req base.Request) {
+	defer sched.wg.Done()
+	defer atomic.AddInt64(&sched.inFlight, -1)
+	defer sched.hostLimiter.release(&req)
 	downloader, err := sched.dlpool.Take()
 	if err != nil {
 		errMsg := fmt.Sprintf("download pool error:%s\n", err)
 		sched.sendError(errors.New(errMsg), SCHEDULER_CODE)
+		return
 	}
 	defer func() {
 		err := sched.dlpool.Return(downloader)
@@ -365,9 +625,24 @@ func (sched *myScheduler) download(req base.Request) {
 	}()
 
 	code := generateCode(DOWNLOADER_CODE, downloader.Id())
-	respp, err := downloader.Download(req)
-	if respp != nil {
-		sched.sendResp(*respp, code)
+	respp, err := downloader.Download(*req.WithContext(sched.ctx))
+	sched.observeDownload(&req, respp, err)
+	if retryErr, ok := err.(dl.RetryError); ok {
+		sched.retry(req, retryErr, code)
+		return
+	}
+	if respp != nil && respp.HttpResp() != nil {
+		// 响应中的链接被保存之后才把请求标记为完成，以免中断时丢失。
+		httpResp := respp.HttpResp()
+		sched.analyzing.Store(httpResp, &req)
+		if !sched.sendResp(*respp, code) {
+			sched.analyzing.Delete(httpResp)
+		}
+	} else {
+		if err == nil {
+			logger.Infof("The request is dropped by a downloader middleware. (requestUrl=%s)\n", req.HttpReq().URL)
+		}
+		sched.reqCache.done(&req)
 	}
 	if err != nil {
 		sched.sendError(err, code)
@@ -381,13 +656,66 @@ func (sched *myScheduler) download(req base.Request) {
 	}()
 }
 
+// 将下载的结果交给自适应限速。
+// 延迟只取下载器测量的网络时间，来自缓存的响应不计入。
+func (sched *myScheduler) observeDownload(req *base.Request, respp *base.Response, err error) {
+	if sched.autoThrottle == nil || sched.ctx.Err() != nil {
+		return
+	}
+	var latency time.Duration
+	var statusCode int
+	if respp != nil && respp.HttpResp() != nil {
+		if respp.Latency() == 0 {
+			return
+		}
+		latency = respp.Latency()
+		statusCode = respp.HttpResp().StatusCode
+	} else if retryErr, ok := err.(dl.RetryError); ok {
+		latency = retryErr.Latency()
+		statusCode = retryErr.StatusCode()
+	}
+	sched.autoThrottle.observe(getHostKey(req), latency, statusCode, dl.IsTimeout(err))
+}
+
+// 在退避时间之后将请求重新放入请求缓存。
+func (sched *myScheduler) retry(req base.Request, retryErr dl.RetryError, code string) {
+	logger.Warnf("Retry the request (retries=%d): %s\n", req.Retries()+1, retryErr)
+	req.SetRetries(req.Retries() + 1)
+	atomic.AddInt32(&sched.retrying, 1)
+	atomic.AddUint64(&sched.retried, 1)
+	sched.wg.Add(1)
+	go func() {
+		defer sched.wg.Done()
+		defer atomic.AddInt32(&sched.retrying, -1)
+		timer := time.NewTimer(retryErr.Delay())
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-sched.ctx.Done():
+			// 使用检查点时，未完成的请求会在恢复时被重新抓取。
+			return
+		}
+		if sched.stopSign.Signed() {
+			sched.stopSign.Deal(code)
+			return
+		}
+		sched.reqCache.put(&req)
+	}()
+}
+
 func (sched *myScheduler) sendResp(resp base.Response, code string) bool {
 	if sched.stopSign.Signed() {
 		sched.stopSign.Deal(code)
 		return false
 	}
-	sched.getRespChan() <- resp
-	return true
+	atomic.AddInt64(&sched.inFlight, 1)
+	select {
+	case sched.getRespChan() <- resp:
+		return true
+	case <-sched.ctx.Done():
+		atomic.AddInt64(&sched.inFlight, -1)
+		return false
+	}
 }
 
 func (sched *myScheduler) getRespChan() chan base.Response {
@@ -411,31 +739,151 @@ func (sched *myScheduler) sendError(err error, code string) bool {
 		errType = base.ANALYZER_ERROR
 	case ITEMPIPELINE_CODE:
 		errType = base.ITEM_PROCCESSOR_ERROR
+	case ROBOTS_CODE:
+		errType = base.ROBOTS_ERROR
 	}
 	cError := base.NewCrawlerError(errType, err.Error())
 	if sched.stopSign.Signed() {
 		sched.stopSign.Deal(code)
 		return false
 	}
+	errChan := sched.getErrChan()
+	sched.wg.Add(1)
 	go func() {
-		sched.getErrChan() <- cError
+		defer sched.wg.Done()
+		select {
+		case errChan <- cError:
+		case <-sched.ctx.Done():
+		}
 	}()
 	return true
 }
 
+// 在上下文被取消之后停止调度器。
+// 通道只会在所有向其发送数据的goroutine都退出之后才被关闭。
+func (sched *myScheduler) watch() {
+	ctx, stopped := sched.ctx, sched.stopped
+	go func() {
+		<-ctx.Done()
+		sched.stopSign.Sign()
+		// 唤醒等待实体的下载和分析，使它们可以结束。
+		sched.dlpool.Close()
+		sched.analyzerPool.Close()
+		sched.wg.Wait()
+		sched.chanman.Close()
+		sched.reqCache.close()
+		atomic.StoreUint32(&sched.running, 2)
+		close(stopped)
+	}()
+}
+
+// 等待停止完成，超时返回false。
+func (sched *myScheduler) waitStopped(timeout time.Duration) bool {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-sched.stopped:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
 func (sched *myScheduler) Stop() bool {
-	if atomic.LoadUint32(&sched.running) != 1 || !sched.stopSign.Sign() || !sched.chanman.Close() {
+	if atomic.LoadUint32(&sched.running) != 1 {
 		return false
 	}
-	sched.reqCache.close()
-	atomic.StoreUint32(&sched.running, 2)
-	return true
+	sched.cancel()
+	return sched.waitStopped(stopTimeout)
+}
+
+func (sched *myScheduler) Drain(timeout time.Duration) error {
+	if atomic.LoadUint32(&sched.running) != 1 {
+		return errors.New("The scheduler is not running!\n")
+	}
+	if !atomic.CompareAndSwapUint32(&sched.draining, 0, 1) {
+		return errors.New("The scheduler is draining!\n")
+	}
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		deadline = timer.C
+	}
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt64(&sched.inFlight) > 0 {
+		select {
+		case <-ticker.C:
+		case <-sched.stopped:
+			return nil
+		case <-deadline:
+			remaining := atomic.LoadInt64(&sched.inFlight)
+			sched.cancel()
+			sched.waitStopped(stopTimeout)
+			errMsg := fmt.Sprintf("Drain timeout after %s, %d unfinished tasks are canceled!\n", timeout, remaining)
+			return errors.New(errMsg)
+		}
+	}
+	sched.cancel()
+	if !sched.waitStopped(stopTimeout) {
+		errMsg := fmt.Sprintf("The scheduler is not stopped within %s!\n", stopTimeout)
+		return errors.New(errMsg)
+	}
+	return nil
+}
+
+func (sched *myScheduler) Pause() bool {
+	if atomic.LoadUint32(&sched.running) != 1 {
+		return false
+	}
+	return atomic.CompareAndSwapUint32(&sched.paused, 0, 1)
+}
+
+func (sched *myScheduler) Resume() bool {
+	if atomic.LoadUint32(&sched.running) != 1 {
+		return false
+	}
+	return atomic.CompareAndSwapUint32(&sched.paused, 1, 0)
+}
+
+func (sched *myScheduler) SetPoolSizes(poolBaseArgs base.PoolBaseArgs) error {
+	if atomic.LoadUint32(&sched.running) != 1 {
+		return errors.New("The scheduler is not running!\n")
+	}
+	if err := poolBaseArgs.Check(); err != nil {
+		errMsg := fmt.Sprintf("Illegal pool base args: %s", err)
+		return errors.New(errMsg)
+	}
+	sched.poolMutex.Lock()
+	defer sched.poolMutex.Unlock()
+	if err := sched.dlpool.Resize(poolBaseArgs.PageDownloaderPoolSize()); err != nil {
+		errMsg := fmt.Sprintf("Occur error when resize page downloader pool: %s\n", err)
+		return errors.New(errMsg)
+	}
+	if err := sched.analyzerPool.Resize(poolBaseArgs.AnalyzerPoolSize()); err != nil {
+		errMsg := fmt.Sprintf("Occur error when resize analyzer pool: %s\n", err)
+		return errors.New(errMsg)
+	}
+	logger.Infof("The pool sizes are changed: %s -> %s\n", sched.poolBaseArgs.String(), poolBaseArgs.String())
+	sched.poolBaseArgs = poolBaseArgs
+	return nil
+}
+
+func (sched *myScheduler) getPoolBaseArgs() base.PoolBaseArgs {
+	sched.poolMutex.Lock()
+	defer sched.poolMutex.Unlock()
+	return sched.poolBaseArgs
 }
 
 func (sched *myScheduler) Running() bool {
 	return atomic.LoadUint32(&sched.running) == 1
 }
 
+func (sched *myScheduler) Paused() bool {
+	return atomic.LoadUint32(&sched.paused) == 1
+}
+
 func (sched *myScheduler) ErrorChan() <-chan error {
 	if sched.chanman.Status() != mdw.CHANNEL_MANAGET_STATUS_INITIALIZED {
 		return nil
@@ -447,7 +895,9 @@ func (sched *myScheduler) Idle() bool {
 	idleDlPool := sched.dlpool.Used() == 0
 	idleAnalyzerPool := sched.analyzerPool.Used() == 0
 	idleItemPipeline := sched.itemPipeline.ProccessingNumber() == 0
-	if idleDlPool && idleAnalyzerPool && idleItemPipeline {
+	idleReqCache := sched.reqCache.length() == 0 && sched.hostLimiter.waiting() == 0 &&
+		atomic.LoadInt32(&sched.retrying) == 0
+	if idleDlPool && idleAnalyzerPool && idleItemPipeline && idleReqCache {
 		return true
 	}
 	return false
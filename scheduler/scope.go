@@ -0,0 +1,168 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 14:36:12
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 14:36:12
+ */
+
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// 抓取范围的规则。
+// 各项规则同时生效，零值表示只抓取种子所在的主域名（包括其子域名）。
+type Scope struct {
+	Anywhere          bool     // 不限制域名，但仍然会应用 UrlPrefixes、Includes 和 Excludes。
+	AllowedDomains    []string // 允许的域名，为空时使用种子所在的主域名。
+	IncludeSubdomains bool     // 是否允许 AllowedDomains 的子域名。
+	UrlPrefixes       []string // URL必须以其中之一开头。
+	Includes          []string // URL必须匹配其中之一的正则表达式。
+	Excludes          []string // URL不能匹配其中任何一个的正则表达式。
+}
+
+type crawlScope interface {
+	// 检查URL是否在抓取范围内，不在时返回原因。
+	check(reqUrl *url.URL) error
+	summary() string
+}
+
+type myCrawlScope struct {
+	anywhere          bool
+	domains           []string
+	includeSubdomains bool
+	prefixes          []string
+	includes          []*regexp.Regexp
+	excludes          []*regexp.Regexp
+}
+
+func compileRegexps(exprs []string) ([]*regexp.Regexp, error) {
+	regs := make([]*regexp.Regexp, 0)
+	for _, expr := range exprs {
+		reg, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, err
+		}
+		regs = append(regs, reg)
+	}
+	return regs, nil
+}
+
+// 检查抓取范围的规则。
+func (scope *Scope) Check() []error {
+	errs := make([]error, 0)
+	for i, domain := range scope.AllowedDomains {
+		if strings.TrimSpace(domain) == "" {
+			errs = append(errs, fmt.Errorf("The %dth allowed domain is empty!\n", i))
+		}
+	}
+	for _, expr := range append(append([]string{}, scope.Includes...), scope.Excludes...) {
+		if _, err := regexp.Compile(expr); err != nil {
+			errs = append(errs, fmt.Errorf("The scope regexp '%s' is invalid: %s\n", expr, err))
+		}
+	}
+	return errs
+}
+
+func newCrawlScope(scope Scope, seeds []*http.Request) (crawlScope, error) {
+	cs := &myCrawlScope{
+		anywhere:          scope.Anywhere,
+		domains:           make([]string, 0),
+		includeSubdomains: scope.IncludeSubdomains,
+		prefixes:          scope.UrlPrefixes,
+	}
+	for _, domain := range scope.AllowedDomains {
+		cs.domains = append(cs.domains, strings.ToLower(strings.TrimSpace(domain)))
+	}
+	if len(cs.domains) == 0 && !cs.anywhere {
+		cs.includeSubdomains = true
+		for _, seed := range seeds {
+			pd, err := getPrimaryDomain(seed.URL.Hostname())
+			if err != nil {
+				errMsg := fmt.Sprintf("The host of seed '%s' is invalid: %s\n", seed.URL, err)
+				return nil, errors.New(errMsg)
+			}
+			cs.domains = appendDomain(cs.domains, pd)
+		}
+	}
+	var err error
+	if cs.includes, err = compileRegexps(scope.Includes); err != nil {
+		return nil, err
+	}
+	if cs.excludes, err = compileRegexps(scope.Excludes); err != nil {
+		return nil, err
+	}
+	return cs, nil
+}
+
+func appendDomain(domains []string, domain string) []string {
+	for _, d := range domains {
+		if d == domain {
+			return domains
+		}
+	}
+	return append(domains, domain)
+}
+
+func (cs *myCrawlScope) check(reqUrl *url.URL) error {
+	urlStr := reqUrl.String()
+	if !cs.anywhere && !cs.matchDomain(strings.ToLower(reqUrl.Hostname())) {
+		return fmt.Errorf("It's host '%s' not in allowed domains %v", reqUrl.Host, cs.domains)
+	}
+	if len(cs.prefixes) > 0 {
+		matched := false
+		for _, prefix := range cs.prefixes {
+			if strings.HasPrefix(urlStr, prefix) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return fmt.Errorf("It's url not start with any of prefixes %v", cs.prefixes)
+		}
+	}
+	if len(cs.includes) > 0 {
+		matched := false
+		for _, reg := range cs.includes {
+			if reg.MatchString(urlStr) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return errors.New("It's url not match any of include patterns")
+		}
+	}
+	for _, reg := range cs.excludes {
+		if reg.MatchString(urlStr) {
+			return fmt.Errorf("It's url match the exclude pattern '%s'", reg)
+		}
+	}
+	return nil
+}
+
+func (cs *myCrawlScope) matchDomain(host string) bool {
+	for _, domain := range cs.domains {
+		if host == domain {
+			return true
+		}
+		if cs.includeSubdomains && strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+var scopeSummaryTemplate = "anywhere: %v, domains: %v, includeSubdomains: %v, " +
+	"prefixes: %d, includes: %d, excludes: %d"
+
+func (cs *myCrawlScope) summary() string {
+	return fmt.Sprintf(scopeSummaryTemplate, cs.anywhere, cs.domains, cs.includeSubdomains,
+		len(cs.prefixes), len(cs.includes), len(cs.excludes))
+}
@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+)
+
+func TestCrawlScope(t *testing.T) {
+	seed, _ := http.NewRequest("GET", "http://www.example.com/", nil)
+	seeds := []*http.Request{seed}
+	cases := []struct {
+		name  string
+		scope Scope
+		url   string
+		want  bool
+	}{
+		{"seed domain", Scope{}, "http://www.example.com/a", true},
+		{"seed subdomain", Scope{}, "http://blog.example.com/a", true},
+		{"other domain", Scope{}, "http://example.org/a", false},
+		{"suffix is not a subdomain", Scope{}, "http://badexample.com/a", false},
+		{"allowed domain", Scope{AllowedDomains: []string{"Example.org"}}, "http://example.org/a", true},
+		{"allowed domain without subdomains", Scope{AllowedDomains: []string{"example.org"}}, "http://www.example.org/a", false},
+		{"allowed subdomains", Scope{AllowedDomains: []string{"example.org"}, IncludeSubdomains: true}, "http://www.example.org/a", true},
+		{"prefix", Scope{UrlPrefixes: []string{"http://www.example.com/docs/"}}, "http://www.example.com/docs/a", true},
+		{"outside prefix", Scope{UrlPrefixes: []string{"http://www.example.com/docs/"}}, "http://www.example.com/blog/a", false},
+		{"include", Scope{Includes: []string{`/\d+$`}}, "http://www.example.com/42", true},
+		{"not included", Scope{Includes: []string{`/\d+$`}}, "http://www.example.com/about", false},
+		{"exclude", Scope{Excludes: []string{`\.pdf$`}}, "http://www.example.com/a.pdf", false},
+		{"anywhere", Scope{Anywhere: true}, "http://example.org/a", true},
+		{"anywhere with include", Scope{Anywhere: true, Includes: []string{`/\d+$`}}, "http://example.org/about", false},
+		{"anywhere with prefix", Scope{Anywhere: true, UrlPrefixes: []string{"https://"}}, "http://example.org/a", false},
+		{"anywhere with exclude", Scope{Anywhere: true, Excludes: []string{`example\.org`}}, "http://example.org/a", false},
+	}
+	for _, c := range cases {
+		cs, err := newCrawlScope(c.scope, seeds)
+		if err != nil {
+			t.Fatalf("%s: %s", c.name, err)
+		}
+		reqUrl, _ := url.Parse(c.url)
+		if err := cs.check(reqUrl); (err == nil) != c.want {
+			t.Errorf("%s: check(%s) = %v, want in scope: %v", c.name, c.url, err, c.want)
+		}
+	}
+}
+
+func TestScopeCheck(t *testing.T) {
+	scope := Scope{AllowedDomains: []string{" "}, Includes: []string{"("}, Excludes: []string{"["}}
+	if errs := scope.Check(); len(errs) != 3 {
+		t.Fatalf("Check() = %v, want 3 errors", errs)
+	}
+	if _, err := newCrawlScope(Scope{Excludes: []string{"["}}, nil); err == nil {
+		t.Fatalf("newCrawlScope() with an invalid pattern should fail")
+	}
+}
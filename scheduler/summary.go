@@ -8,8 +8,8 @@
 package scheduler
 
 import (
-	"bytes"
 	"fmt"
+	"sync/atomic"
 	base "webcrawler/base"
 )
 
@@ -23,55 +23,92 @@ func NewSchedSummary(sched *myScheduler, prefix string) SchedSummary {
 	if sched == nil {
 		return nil
 	}
-	urlCount := len(sched.urlMap)
-	var urlDetail string
-	if urlCount > 0 {
-		var buffer bytes.Buffer
-		buffer.WriteByte('\n')
-		for url, _ := range sched.urlMap {
-			buffer.WriteString(prefix)
-			buffer.WriteString(prefix)
-			buffer.WriteString(url)
-			buffer.WriteByte('\n')
-		}
-		urlDetail = buffer.String()
-	} else {
-		urlDetail = "\n"
-	}
 	return &mySchedSummary{
-		prefix:              prefix,
-		running:             sched.running,
-		channelArgs:         sched.channelArgs,
-		poolBaseArgs:        sched.poolBaseArgs,
-		crawlDepth:          sched.crawlDepth,
-		chanmanSummary:      sched.chanman.Summary(),
-		reqCacheSummary:     sched.reqCache.summary(),
+		prefix:             prefix,
+		running:            sched.running,
+		paused:             atomic.LoadUint32(&sched.paused),
+		channelArgs:        sched.channelArgs,
+		poolBaseArgs:       sched.getPoolBaseArgs(),
+		crawlDepth:         sched.crawlDepth,
+		scopeSummary:       sched.scope.summary(),
+		chanmanSummary:     sched.chanman.Summary(),
+		reqCacheSummary:    sched.reqCache.summary(),
+		hostLimiterSummary: sched.hostLimiter.summary(),
+		autoThrottleSummary: func() string {
+			if sched.autoThrottle == nil {
+				return "disabled"
+			}
+			return sched.autoThrottle.summary()
+		}(),
+		rateLimitSummary: func() string {
+			if sched.rateLimiter == nil {
+				return "disabled"
+			}
+			return sched.rateLimiter.String()
+		}(),
+		httpCacheSummary: func() string {
+			if sched.httpCache == nil {
+				return "disabled"
+			}
+			return sched.httpCache.Summary()
+		}(),
+		warcSummary: func() string {
+			if sched.warcWriter == nil {
+				return "disabled"
+			}
+			return sched.warcWriter.Summary()
+		}(),
+		robotsSummary: func() string {
+			if sched.robotsCache == nil {
+				return "disabled"
+			}
+			return sched.robotsCache.Summary()
+		}(),
+		retrySummary: func() string {
+			policy := "disabled"
+			if sched.retryPolicy != nil {
+				policy = sched.retryPolicy.String()
+			}
+			return fmt.Sprintf(retrySummaryTemplate, policy,
+				atomic.LoadInt32(&sched.retrying), atomic.LoadUint64(&sched.retried))
+		}(),
 		dlPoolLen:           sched.dlpool.Used(),
 		dlPoolCap:           sched.dlpool.Total(),
 		analyzerPoolLen:     sched.analyzerPool.Used(),
 		analyzerPoolCap:     sched.analyzerPool.Total(),
 		itemPipelineSummary: sched.itemPipeline.Summary(),
-		urlCount:            urlCount,
-		urlDetail:           urlDetail,
+		urlCount:            sched.seenSet.Count(),
+		seenSetSummary:      sched.seenSet.Summary(),
 		stopSignSummary:     sched.stopSign.Summary(),
 	}
 }
 
+var retrySummaryTemplate = "policy: %s, retrying: %d, retried: %d"
+
 type mySchedSummary struct {
 	prefix              string // 前缀。
 	running             uint32 // 运行标记。
+	paused              uint32 // 暂停标记。
 	crawlDepth          uint32 // 爬取的最大深度。
+	scopeSummary        string // 抓取范围的摘要信息。
 	channelArgs         base.ChannelArgs
 	poolBaseArgs        base.PoolBaseArgs
 	chanmanSummary      string // 通道管理器的摘要信息。
 	reqCacheSummary     string // 请求缓存的摘要信息。
+	hostLimiterSummary  string // 主机限制器的摘要信息。
+	autoThrottleSummary string // 自适应限速的摘要信息。
+	rateLimitSummary    string // 速率限制的摘要信息。
+	httpCacheSummary    string // HTTP缓存的摘要信息。
+	warcSummary         string // WARC归档的摘要信息。
+	robotsSummary       string // robots缓存的摘要信息。
+	retrySummary        string // 重试的摘要信息。
 	dlPoolLen           uint32 // 网页下载器池的长度。
 	dlPoolCap           uint32 // 网页下载器池的容量。
 	analyzerPoolLen     uint32 // 分析器池的长度。
 	analyzerPoolCap     uint32 // 分析器池的容量。
 	itemPipelineSummary string // 条目处理管道的摘要信息。
-	urlCount            int    // 已请求的URL的计数。
-	urlDetail           string // 已请求的URL的详细信息。
+	urlCount            uint64 // 已请求的URL的计数。
+	seenSetSummary      string // URL去重集合的摘要信息。
 	stopSignSummary     string // 停止信号的摘要信息。
 }
 
@@ -87,34 +124,52 @@ func (ss *mySchedSummary) Detail() string {
 func (ss *mySchedSummary) getSummary(detail bool) string {
 	prefix := ss.prefix
 	template := prefix + "Running: %v \n" +
+		prefix + "Paused: %v \n" +
 		prefix + "Channel args: %d \n" +
 		prefix + "Pool base args: %d \n" +
 		prefix + "Crawl depth: %d \n" +
+		prefix + "Scope: %s\n" +
 		prefix + "Channels manager: %s \n" +
 		prefix + "Request cache: %s\n" +
+		prefix + "Host limiter: %s\n" +
+		prefix + "Auto throttle: %s\n" +
+		prefix + "Rate limit: %s\n" +
+		prefix + "Http cache: %s\n" +
+		prefix + "Warc: %s\n" +
+		prefix + "Robots: %s\n" +
+		prefix + "Retries: %s\n" +
 		prefix + "Downloader pool: %d/%d\n" +
 		prefix + "Analyzer pool: %d/%d\n" +
 		prefix + "Item pipeline: %s\n" +
-		prefix + "Urls(%d): %s" +
+		prefix + "Urls(%d): %s\n" +
 		prefix + "Stop sign: %s\n"
 	return fmt.Sprintf(template,
 		func() bool {
 			return ss.running == 1
 		}(),
+		ss.paused == 1,
 		ss.channelArgs,
 		ss.poolBaseArgs,
 		ss.crawlDepth,
+		ss.scopeSummary,
 		ss.chanmanSummary,
 		ss.reqCacheSummary,
+		ss.hostLimiterSummary,
+		ss.autoThrottleSummary,
+		ss.rateLimitSummary,
+		ss.httpCacheSummary,
+		ss.warcSummary,
+		ss.robotsSummary,
+		ss.retrySummary,
 		ss.dlPoolLen, ss.dlPoolCap,
 		ss.analyzerPoolLen, ss.analyzerPoolCap,
 		ss.itemPipelineSummary,
 		ss.urlCount,
 		func() string {
 			if detail {
-				return ss.urlDetail
+				return ss.seenSetSummary
 			} else {
-				return "<concealed>\n"
+				return "<concealed>"
 			}
 		}(),
 		ss.stopSignSummary)
@@ -129,14 +184,24 @@ func (ss *mySchedSummary) Same(other SchedSummary) bool {
 		return false
 	}
 	if ss.running != otherSs.running ||
+		ss.paused != otherSs.paused ||
 		ss.crawlDepth != otherSs.crawlDepth ||
+		ss.scopeSummary != otherSs.scopeSummary ||
 		ss.dlPoolLen != otherSs.dlPoolLen ||
 		ss.dlPoolCap != otherSs.dlPoolCap ||
 		ss.analyzerPoolLen != otherSs.analyzerPoolLen ||
 		ss.analyzerPoolCap != otherSs.analyzerPoolCap ||
 		ss.urlCount != otherSs.urlCount ||
+		ss.seenSetSummary != otherSs.seenSetSummary ||
 		ss.stopSignSummary != otherSs.stopSignSummary ||
 		ss.reqCacheSummary != otherSs.reqCacheSummary ||
+		ss.hostLimiterSummary != otherSs.hostLimiterSummary ||
+		ss.autoThrottleSummary != otherSs.autoThrottleSummary ||
+		ss.rateLimitSummary != otherSs.rateLimitSummary ||
+		ss.httpCacheSummary != otherSs.httpCacheSummary ||
+		ss.warcSummary != otherSs.warcSummary ||
+		ss.robotsSummary != otherSs.robotsSummary ||
+		ss.retrySummary != otherSs.retrySummary ||
 		ss.poolBaseArgs != otherSs.poolBaseArgs ||
 		ss.channelArgs != otherSs.channelArgs ||
 		ss.itemPipelineSummary != otherSs.itemPipelineSummary ||
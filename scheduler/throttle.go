@@ -0,0 +1,159 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 19:12:40
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 19:12:40
+ */
+
+package scheduler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	base "webcrawler/base"
+)
+
+const (
+	throttleDecreaseFactor = 0.5                    // 拥塞时并发数乘以的系数。
+	throttleDelayStep      = 100 * time.Millisecond // 抓取间隔每次减少的量，也是拥塞时的最小间隔。
+	throttleLatencyWeight  = 0.2                    // 延迟的指数移动平均中新样本的权重。
+)
+
+// 自适应限速。
+// 根据每个主机的响应延迟、429/503响应和超时，以加性增、乘性减（AIMD）的方式
+// 调整该主机的并发数和抓取间隔，并交给主机限制器执行。
+type autoThrottle interface {
+	// 记录一次下载的结果，statusCode为0表示没有响应，latency为0表示没有测量到延迟。
+	observe(host string, latency time.Duration, statusCode int, timeout bool)
+	summary() string
+}
+
+type throttleState struct {
+	concurrency  float64
+	delay        time.Duration
+	latency      time.Duration // 延迟的指数移动平均值。
+	responses    uint64
+	congestions  uint64
+	lastDecrease time.Time
+	lastObserved time.Time
+}
+
+type myAutoThrottle struct {
+	args    base.AutoThrottleArgs
+	limiter hostLimiter
+	hosts   map[string]*throttleState
+	sweeper idleSweeper
+	mutex   sync.Mutex
+}
+
+func newAutoThrottle(args base.AutoThrottleArgs, limiter hostLimiter) autoThrottle {
+	return &myAutoThrottle{
+		args:    args,
+		limiter: limiter,
+		hosts:   make(map[string]*throttleState),
+	}
+}
+
+// 是否是目标站点过载的信号。
+func (at *myAutoThrottle) congested(latency time.Duration, statusCode int, timeout bool) bool {
+	return timeout ||
+		statusCode == http.StatusTooManyRequests ||
+		statusCode == http.StatusServiceUnavailable ||
+		latency > at.args.TargetLatency()
+}
+
+func (at *myAutoThrottle) observe(host string, latency time.Duration, statusCode int, timeout bool) {
+	host = strings.ToLower(host)
+	at.mutex.Lock()
+	defer at.mutex.Unlock()
+	now := time.Now()
+	if at.sweeper.due(now) {
+		at.sweep(now)
+	}
+	state, ok := at.hosts[host]
+	if !ok {
+		state = &throttleState{concurrency: 1, delay: at.args.MinDelay(), latency: latency}
+		at.hosts[host] = state
+	}
+	state.lastObserved = now
+	state.responses++
+	if latency > 0 {
+		state.latency += time.Duration(throttleLatencyWeight * float64(latency-state.latency))
+	}
+
+	if at.congested(latency, statusCode, timeout) {
+		state.congestions++
+		// 同一批请求的拥塞信号只处理一次。
+		window := state.latency
+		if window < at.args.TargetLatency() {
+			window = at.args.TargetLatency()
+		}
+		if now.Sub(state.lastDecrease) < window {
+			return
+		}
+		state.lastDecrease = now
+		state.concurrency *= throttleDecreaseFactor
+		if state.concurrency < 1 {
+			state.concurrency = 1
+		}
+		state.delay *= 2
+		if state.delay < throttleDelayStep {
+			state.delay = throttleDelayStep
+		}
+		if state.delay > at.args.MaxDelay() {
+			state.delay = at.args.MaxDelay()
+		}
+	} else {
+		// 每一轮（约concurrency个响应）并发数加1。
+		state.concurrency += 1 / state.concurrency
+		if max := float64(at.args.MaxConcurrency()); state.concurrency > max {
+			state.concurrency = max
+		}
+		state.delay -= throttleDelayStep
+		if state.delay < at.args.MinDelay() {
+			state.delay = at.args.MinDelay()
+		}
+	}
+	at.limiter.setHostThrottle(host, uint32(state.concurrency), state.delay)
+}
+
+// 清除闲置的主机状态，并解除主机限制器中对应的设置，需要在持有锁的情况下调用。
+// 与主机限制器使用相同的清除策略，主机在闲置之后重新从初始的并发数和抓取间隔开始。
+func (at *myAutoThrottle) sweep(now time.Time) {
+	for host, state := range at.hosts {
+		if hostIdle(state.lastObserved, now) {
+			delete(at.hosts, host)
+			at.limiter.setHostThrottle(host, 0, 0)
+		}
+	}
+}
+
+var autoThrottleSummaryTemplate = "args: %s, hosts: %s"
+
+func (at *myAutoThrottle) summary() string {
+	at.mutex.Lock()
+	defer at.mutex.Unlock()
+	hosts := make([]string, 0, len(at.hosts))
+	for host := range at.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	var buffer bytes.Buffer
+	buffer.WriteByte('[')
+	for i, host := range hosts {
+		if i > 0 {
+			buffer.WriteString(", ")
+		}
+		state := at.hosts[host]
+		buffer.WriteString(fmt.Sprintf("%s(concurrency: %d, delay: %s, latency: %s, congestion: %d/%d)",
+			host, uint32(state.concurrency), state.delay,
+			state.latency.Round(time.Millisecond), state.congestions, state.responses))
+	}
+	buffer.WriteByte(']')
+	return fmt.Sprintf(autoThrottleSummaryTemplate, at.args.String(), buffer.String())
+}
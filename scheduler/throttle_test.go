@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"net/http"
+	"testing"
+	"time"
+	"webcrawler/base"
+)
+
+func TestAutoThrottleBacksOff(t *testing.T) {
+	limiter := newHostLimiter(base.NewHostLimitArgs(0, 0, 0)).(*myHostLimiter)
+	throttle := newAutoThrottle(base.NewAutoThrottleArgs(time.Second, 8, 0, 10*time.Second), limiter).(*myAutoThrottle)
+	for i := 0; i < 20; i++ {
+		throttle.observe("a.com", 10*time.Millisecond, http.StatusOK, false)
+	}
+	hs := limiter.hosts["a.com"]
+	if hs.throttleInFlight <= 1 || hs.throttleDelay != 0 {
+		t.Fatalf("The throttle did not speed up: inFlight=%d, delay=%s", hs.throttleInFlight, hs.throttleDelay)
+	}
+	before := hs.throttleInFlight
+	throttle.observe("a.com", 0, http.StatusServiceUnavailable, false)
+	if hs.throttleInFlight >= before || hs.throttleDelay < throttleDelayStep {
+		t.Fatalf("The throttle did not back off: inFlight=%d, delay=%s", hs.throttleInFlight, hs.throttleDelay)
+	}
+}
+
+func TestAutoThrottleSweepsIdleHosts(t *testing.T) {
+	limiter := newHostLimiter(base.NewHostLimitArgs(0, 0, 0)).(*myHostLimiter)
+	throttle := newAutoThrottle(base.NewAutoThrottleArgs(time.Second, 8, 0, 10*time.Second), limiter).(*myAutoThrottle)
+	throttle.observe("a.com", 0, http.StatusServiceUnavailable, false)
+	throttle.observe("b.com", 0, http.StatusServiceUnavailable, false)
+	now := time.Now().Add(hostStateIdle)
+	throttle.hosts["b.com"].lastObserved = now
+
+	throttle.mutex.Lock()
+	throttle.sweep(now)
+	throttle.mutex.Unlock()
+	if _, ok := throttle.hosts["a.com"]; ok {
+		t.Fatalf("The idle throttle state is not swept")
+	}
+	if _, ok := throttle.hosts["b.com"]; !ok {
+		t.Fatalf("The active throttle state is swept")
+	}
+	// 解除限速设置之后，主机限制器中的状态也可以被清除。
+	if hs := limiter.hosts["a.com"]; hs.throttleInFlight != 0 || hs.throttleDelay != 0 {
+		t.Fatalf("The limiter still throttles the swept host: inFlight=%d, delay=%s", hs.throttleInFlight, hs.throttleDelay)
+	}
+	if hs := limiter.hosts["b.com"]; hs.throttleDelay == 0 {
+		t.Fatalf("The limiter lost the throttle of the active host")
+	}
+}
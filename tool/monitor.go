@@ -134,7 +134,8 @@ func checkStatus(scheduler sched.Scheduler, intervalNs time.Duration, maxIdleCou
 		var idleCount uint
 		var firstIdleTime time.Time
 		for {
-			if scheduler.Idle() {
+			// 暂停的调度器不算作空闲。
+			if !scheduler.Paused() && scheduler.Idle() {
 				idleCount++
 				if idleCount == 1 {
 					firstIdleTime = time.Now()
@@ -142,7 +143,7 @@ func checkStatus(scheduler sched.Scheduler, intervalNs time.Duration, maxIdleCou
 				if idleCount > maxIdleCount {
 					msg := fmt.Sprintf(msgReachMaxIdleCount, time.Since(firstIdleTime).String())
 					record(0, msg)
-					if scheduler.Idle() {
+					if !scheduler.Paused() && scheduler.Idle() {
 						if autoStop {
 							var result string
 							if scheduler.Stop() {
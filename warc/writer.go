@@ -0,0 +1,329 @@
+/*
+* @Author: wangshuo
+* @Date:   2026-10-18 20:58:33
+* @Last Modified by:   wangshuo
+* @Last Modified time: 2026-10-18 20:58:33
+ */
+
+package warc
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	warcVersion       = "WARC/1.1"
+	warcConformsTo    = "http://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/"
+	warcSoftware      = "webcrawler"
+	openFileSuffix    = ".open"
+	warcFileExtension = ".warc.gz"
+)
+
+// 一次HTTP交换，即一对请求和响应。
+type Exchange struct {
+	Request      *http.Request
+	RequestBody  []byte
+	Response     *http.Response
+	ResponseBody []byte
+	Truncated    bool          // ResponseBody 是否只是响应体的开头部分（超出了长度限制）。
+	FetchTime    time.Time     // 开始请求的时间。
+	FetchElapsed time.Duration // 请求所用的时间。
+	Depth        uint32        // 距种子的跳数。
+	Via          string        // 发现该URL的页面。
+}
+
+// WARC 1.1 文件的写入器。
+// 每次交换写入request、response和metadata三条记录，每条记录单独gzip压缩。
+// 实现需要是并发安全的。
+type Writer interface {
+	// 写入一次交换，写入器被关闭之后返回错误。
+	WriteExchange(exchange *Exchange) error
+	Summary() string
+	Close() error
+}
+
+type myWriter struct {
+	dir         string
+	prefix      string
+	maxFileSize int64
+	serial      uint32
+	file        *os.File
+	path        string
+	size        int64
+	files       uint32
+	records     uint64
+	closed      bool
+	mutex       sync.Mutex
+}
+
+// 文件在 dir 下以 prefix-时间-序号.warc.gz 命名，写入中的文件带有.open后缀。
+// 文件大小超过 maxFileSize 之后会写入新的文件，maxFileSize为0时不分割。
+func NewWriter(dir string, prefix string, maxFileSize int64) (Writer, error) {
+	if dir == "" {
+		return nil, errors.New("The WARC directory is invalid!\n")
+	}
+	if prefix == "" {
+		return nil, errors.New("The WARC file prefix is invalid!\n")
+	}
+	if maxFileSize < 0 {
+		errMsg := fmt.Sprintf("The max WARC file size can not be negative! (maxFileSize=%d)\n", maxFileSize)
+		return nil, errors.New(errMsg)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &myWriter{dir: dir, prefix: prefix, maxFileSize: maxFileSize}, nil
+}
+
+func newRecordId() string {
+	uuid := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, uuid); err != nil {
+		panic(err)
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
+
+func formatDate(t time.Time) string {
+	return t.UTC().Format("2006-01-02T15:04:05.000000Z")
+}
+
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+type header struct {
+	name  string
+	value string
+}
+
+type record struct {
+	headers []header
+	block   []byte
+}
+
+func (rec *record) add(name string, value string) {
+	rec.headers = append(rec.headers, header{name, value})
+}
+
+// 以单独的gzip成员写入一条记录。
+func (rec *record) writeTo(w io.Writer) error {
+	gz := gzip.NewWriter(w)
+	bw := bufio.NewWriter(gz)
+	bw.WriteString(warcVersion + "\r\n")
+	for _, h := range rec.headers {
+		bw.WriteString(h.name + ": " + h.value + "\r\n")
+	}
+	bw.WriteString("WARC-Block-Digest: " + digest(rec.block) + "\r\n")
+	bw.WriteString("Content-Length: " + strconv.Itoa(len(rec.block)) + "\r\n\r\n")
+	bw.Write(rec.block)
+	bw.WriteString("\r\n\r\n")
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+func writeHeaders(buffer *bytes.Buffer, h http.Header, skip map[string]bool) {
+	names := make([]string, 0, len(h))
+	for name := range h {
+		if !skip[http.CanonicalHeaderKey(name)] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range h[name] {
+			buffer.WriteString(name + ": " + value + "\r\n")
+		}
+	}
+}
+
+// 重建HTTP请求报文。
+// net/http不保留原始报文，由Transport添加的头不会出现在其中。
+func requestBlock(req *http.Request, body []byte) []byte {
+	var buffer bytes.Buffer
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	buffer.WriteString(method + " " + req.URL.RequestURI() + " HTTP/1.1\r\n")
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	buffer.WriteString("Host: " + host + "\r\n")
+	writeHeaders(&buffer, req.Header, map[string]bool{"Host": true})
+	buffer.WriteString("\r\n")
+	buffer.Write(body)
+	return buffer.Bytes()
+}
+
+// 重建HTTP响应报文。
+// Transport自动解压时响应体是解压之后的，此时去掉Content-Encoding。
+// Content-Length总是按实际的响应体重新计算。
+func responseBlock(resp *http.Response, body []byte) []byte {
+	var buffer bytes.Buffer
+	status := resp.Status
+	if status == "" {
+		status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	buffer.WriteString("HTTP/1.1 " + status + "\r\n")
+	skip := map[string]bool{"Content-Length": true, "Transfer-Encoding": true}
+	if resp.Uncompressed {
+		skip["Content-Encoding"] = true
+	}
+	writeHeaders(&buffer, resp.Header, skip)
+	buffer.WriteString("Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n")
+	buffer.Write(body)
+	return buffer.Bytes()
+}
+
+func metadataBlock(exchange *Exchange) []byte {
+	var buffer bytes.Buffer
+	if exchange.Via != "" {
+		buffer.WriteString("via: " + exchange.Via + "\r\n")
+	}
+	buffer.WriteString("hopsFromSeed: " + strconv.FormatUint(uint64(exchange.Depth), 10) + "\r\n")
+	buffer.WriteString("fetchTimeMs: " + strconv.FormatInt(int64(exchange.FetchElapsed/time.Millisecond), 10) + "\r\n")
+	return buffer.Bytes()
+}
+
+// 打开新的文件并写入warcinfo记录，调用方需持有锁。
+func (w *myWriter) rotate() error {
+	if err := w.closeFile(); err != nil {
+		return err
+	}
+	w.serial++
+	name := fmt.Sprintf("%s-%s-%05d%s", w.prefix, time.Now().UTC().Format("20060102150405"), w.serial, warcFileExtension)
+	path := filepath.Join(w.dir, name)
+	file, err := os.OpenFile(path+openFileSuffix, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	w.file, w.path, w.size = file, path, 0
+	w.files++
+
+	var fields bytes.Buffer
+	fields.WriteString("software: " + warcSoftware + "\r\n")
+	fields.WriteString("format: WARC File Format 1.1\r\n")
+	fields.WriteString("conformsTo: " + warcConformsTo + "\r\n")
+	info := &record{block: fields.Bytes()}
+	info.add("WARC-Type", "warcinfo")
+	info.add("WARC-Record-ID", newRecordId())
+	info.add("WARC-Date", formatDate(time.Now()))
+	info.add("WARC-Filename", name)
+	info.add("Content-Type", "application/warc-fields")
+	return w.write(info)
+}
+
+func (w *myWriter) write(rec *record) error {
+	var buffer bytes.Buffer
+	if err := rec.writeTo(&buffer); err != nil {
+		return err
+	}
+	n, err := w.file.Write(buffer.Bytes())
+	w.size += int64(n)
+	if err == nil {
+		w.records++
+	}
+	return err
+}
+
+func (w *myWriter) WriteExchange(exchange *Exchange) error {
+	if exchange == nil || exchange.Request == nil || exchange.Response == nil {
+		return errors.New("The WARC exchange is invalid!\n")
+	}
+	targetUri := exchange.Request.URL.String()
+	date := formatDate(exchange.FetchTime)
+	responseId := newRecordId()
+
+	response := &record{block: responseBlock(exchange.Response, exchange.ResponseBody)}
+	response.add("WARC-Type", "response")
+	response.add("WARC-Record-ID", responseId)
+	response.add("WARC-Date", date)
+	response.add("WARC-Target-URI", targetUri)
+	response.add("WARC-Payload-Digest", digest(exchange.ResponseBody))
+	if exchange.Truncated {
+		response.add("WARC-Truncated", "length")
+	}
+	response.add("Content-Type", "application/http;msgtype=response")
+
+	request := &record{block: requestBlock(exchange.Request, exchange.RequestBody)}
+	request.add("WARC-Type", "request")
+	request.add("WARC-Record-ID", newRecordId())
+	request.add("WARC-Date", date)
+	request.add("WARC-Target-URI", targetUri)
+	request.add("WARC-Concurrent-To", responseId)
+	request.add("Content-Type", "application/http;msgtype=request")
+
+	metadata := &record{block: metadataBlock(exchange)}
+	metadata.add("WARC-Type", "metadata")
+	metadata.add("WARC-Record-ID", newRecordId())
+	metadata.add("WARC-Date", date)
+	metadata.add("WARC-Target-URI", targetUri)
+	metadata.add("WARC-Concurrent-To", responseId)
+	metadata.add("Content-Type", "application/warc-fields")
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return errors.New("The WARC writer is closed!\n")
+	}
+	if w.file == nil || (w.maxFileSize > 0 && w.size >= w.maxFileSize) {
+		if err := w.rotate(); err != nil {
+			return err
+		}
+	}
+	for _, rec := range []*record{response, request, metadata} {
+		if err := w.write(rec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// 关闭当前文件并去掉.open后缀，调用方需持有锁。
+func (w *myWriter) closeFile() error {
+	if w.file == nil {
+		return nil
+	}
+	file := w.file
+	w.file = nil
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(w.path+openFileSuffix, w.path)
+}
+
+var writerSummaryTemplate = "dir: %s, files: %d, records: %d, current: %s(%d bytes)"
+
+func (w *myWriter) Summary() string {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return fmt.Sprintf(writerSummaryTemplate, w.dir, w.files, w.records, filepath.Base(w.path), w.size)
+}
+
+func (w *myWriter) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+	return w.closeFile()
+}
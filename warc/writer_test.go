@@ -0,0 +1,174 @@
+package warc
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestExchange(t *testing.T, body string, truncated bool) *Exchange {
+	httpReq, err := http.NewRequest("GET", "http://example.com/page?a=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq.Header.Set("User-Agent", "webcrawler")
+	httpResp := &http.Response{
+		Status:     "200 OK",
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"text/html"}, "Content-Length": {"999"}},
+		Request:    httpReq,
+	}
+	return &Exchange{
+		Request:      httpReq,
+		Response:     httpResp,
+		ResponseBody: []byte(body),
+		Truncated:    truncated,
+		FetchTime:    time.Now(),
+		FetchElapsed: 20 * time.Millisecond,
+		Depth:        1,
+		Via:          "http://example.com/",
+	}
+}
+
+// 读取WARC文件中所有记录的头，每条记录是单独的gzip成员。
+func readRecords(t *testing.T, path string) []map[string]string {
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader := bufio.NewReader(gz)
+	records := make([]map[string]string, 0)
+	for {
+		version, err := reader.ReadString('\n')
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != warcVersion+"\r\n" {
+			t.Fatalf("Unexpected record start %q", version)
+		}
+		headers := make(map[string]string)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatal(err)
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if line == "" {
+				break
+			}
+			parts := strings.SplitN(line, ": ", 2)
+			headers[parts[0]] = parts[1]
+		}
+		var length int64
+		for _, c := range headers["Content-Length"] {
+			length = length*10 + int64(c-'0')
+		}
+		block := make([]byte, length+4)
+		if _, err := io.ReadFull(reader, block); err != nil {
+			t.Fatal(err)
+		}
+		headers["block"] = string(block[:length])
+		records = append(records, headers)
+	}
+	return records
+}
+
+func TestWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writer, err := NewWriter(dir, "test", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteExchange(newTestExchange(t, "<html>hello</html>", false)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteExchange(newTestExchange(t, "<html>cut", true)); err != nil {
+		t.Fatal(err)
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*"+openFileSuffix)); len(matches) != 1 {
+		t.Fatalf("open files = %v, want one", matches)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.WriteExchange(newTestExchange(t, "late", false)); err == nil {
+		t.Fatalf("WriteExchange() after Close() should fail")
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 || !strings.HasSuffix(files[0], warcFileExtension) {
+		t.Fatalf("files = %v, want one closed WARC file", files)
+	}
+	records := readRecords(t, files[0])
+	types := make([]string, 0, len(records))
+	for _, rec := range records {
+		types = append(types, rec["WARC-Type"])
+	}
+	if strings.Join(types, ",") != "warcinfo,response,request,metadata,response,request,metadata" {
+		t.Fatalf("record types = %v", types)
+	}
+	response := records[1]
+	if response["WARC-Target-URI"] != "http://example.com/page?a=1" || response["WARC-Truncated"] != "" {
+		t.Fatalf("Unexpected response record: %v", response)
+	}
+	if !strings.HasSuffix(response["block"], "Content-Length: 18\r\n\r\n<html>hello</html>") {
+		t.Fatalf("Unexpected response block: %q", response["block"])
+	}
+	if records[2]["WARC-Concurrent-To"] != response["WARC-Record-ID"] {
+		t.Fatalf("The request record is not linked to the response")
+	}
+	if !strings.Contains(records[3]["block"], "via: http://example.com/\r\n") {
+		t.Fatalf("Unexpected metadata block: %q", records[3]["block"])
+	}
+	if records[4]["WARC-Truncated"] != "length" {
+		t.Fatalf("The truncated response is not marked: %v", records[4])
+	}
+}
+
+func TestWriterRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "warc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writer, err := NewWriter(dir, "test", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := writer.WriteExchange(newTestExchange(t, "body", false)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+warcFileExtension))
+	if len(files) != 3 {
+		t.Fatalf("files = %v, want 3", files)
+	}
+	for _, file := range files {
+		if records := readRecords(t, file); len(records) != 4 || records[0]["WARC-Type"] != "warcinfo" {
+			t.Fatalf("Unexpected records in %s", file)
+		}
+	}
+}
@@ -8,7 +8,10 @@
 package main
 
 import (
+	"context"
+	_ "embed"
 	"errors"
+	"flag"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
 	"io"
@@ -16,33 +19,48 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
-	"strings"
 	"sync/atomic"
 	"time"
 	"webcrawler/analyzer"
 	base "webcrawler/base"
+	dl "webcrawler/downloader"
 	pipeline "webcrawler/itempipeline"
 	sched "webcrawler/scheduler"
 	"webcrawler/tool"
 )
 
 var (
-	logger  logging.Logger = logging.NewSimpleLogger()
-	gotPage bool           = false
-	count   uint32
+	logger logging.Logger = logging.NewSimpleLogger()
+	count  uint32
+
+	recordDir = flag.String("record", "", "record responses into the fixture directory")
+	replayDir = flag.String("replay", "", "replay responses from the fixture directory without network access")
+	rulesPath = flag.String("rules", "", "the item extraction rules file (YAML or JSON), the built-in rules are used when empty")
 )
 
+// 内置的item提取规则，不依赖于运行时的工作目录。
+//
+//go:embed items.yaml
+var builtinRules []byte
+
 func genHttpClient() *http.Client {
-	return &http.Client{}
+	client := &http.Client{}
+	var transport http.RoundTripper
+	var err error
+	if *replayDir != "" {
+		transport, err = dl.NewReplayTransport(*replayDir)
+	} else if *recordDir != "" {
+		transport, err = dl.NewRecordTransport(*recordDir, nil)
+	}
+	if err != nil {
+		panic(err)
+	}
+	client.Transport = transport
+	return client
 }
 
 func main() {
-	channelArgs := base.NewChannelArgs(10, 10, 10, 10)
-	poolBaseArgs := base.NewPoolBaseArgs(8, 3)
-	crawlDepth := uint32(3)
-	httpClientGenerator := genHttpClient
-	respParsers := getResponseParsers()
-	itemProcessors := getItemProcessors()
+	flag.Parse()
 	startUrl := "https://www.zhihu.com/collection/20615676"
 	// startUrl := "https://www.zhihu.com/collection/139296034"
 	// startUrl := "https://www.zhihu.com/collection/75387977"
@@ -53,13 +71,32 @@ func main() {
 		return
 	}
 
+	respParsers, err := getResponseParsers()
+	if err != nil {
+		logger.Errorln(err)
+		return
+	}
+
 	scheduler := sched.NewScheduler()
 
 	intervalNs := 10 * time.Millisecond
 	maxIdleCount := uint(1000)
 	checkCountChan := tool.Monitoring(scheduler, intervalNs, maxIdleCount, true, false, record)
 
-	scheduler.Start(channelArgs, poolBaseArgs, crawlDepth, httpClientGenerator, respParsers, itemProcessors, firstHttpReq)
+	config := sched.Config{
+		ChannelArgs:         base.NewChannelArgs(10, 10, 10, 10),
+		PoolBaseArgs:        base.NewPoolBaseArgs(8, 3),
+		CrawlDepth:          3,
+		HttpClientGenerator: genHttpClient,
+		RespParsers:         respParsers,
+		ItemProcessors:      getItemProcessors(),
+		Seeds:               []*http.Request{firstHttpReq},
+		FrontierOrder:       sched.FRONTIER_PRIORITY,
+	}
+	if err := scheduler.Start(context.Background(), config); err != nil {
+		logger.Errorln(err)
+		return
+	}
 
 	<-checkCountChan
 	fmt.Printf("count:%d\n", count)
@@ -79,12 +116,39 @@ func record(level byte, content string) {
 	}
 }
 
-func getResponseParsers() []analyzer.ParseResponse {
+func getResponseParsers() ([]analyzer.ParseResponse, error) {
+	var rules analyzer.ItemRules
+	var err error
+	if *rulesPath != "" {
+		rules, err = analyzer.LoadItemRules(*rulesPath)
+	} else {
+		rules, err = analyzer.ParseItemRules(builtinRules, "yaml")
+	}
+	if err != nil {
+		return nil, err
+	}
+	itemExtractor, err := analyzer.NewItemExtractor(rules)
+	if err != nil {
+		return nil, err
+	}
+	// 收藏夹页面和回答页面分别使用不同的解析函数。
+	router, err := analyzer.NewRouter(
+		analyzer.Route{
+			Pattern: `/collection/\d+`,
+			Parsers: []analyzer.ParseResponse{parseForPage, itemExtractor.Parse},
+		},
+		analyzer.Route{
+			Pattern: `/question/\d+/answer/\d+`,
+			Parsers: []analyzer.ParseResponse{parseForAnswer},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
 	parsers := []analyzer.ParseResponse{
-		parseForRequest,
-		// parseForAnswer,
+		router.Parse,
 	}
-	return parsers
+	return parsers, nil
 }
 
 func parseForAnswer(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
@@ -92,16 +156,7 @@ func parseForAnswer(httpResp *http.Response, respDepth uint32) ([]base.Data, []e
 		err := errors.New(fmt.Sprintf("Unsupported status code %d. (httpResponse=%v)", httpResp))
 		return nil, []error{err}
 	}
-	var reqUrl *url.URL = httpResp.Request.URL
-	if !strings.Contains(reqUrl.String(), "answer") {
-		return nil, []error{}
-	}
-	var httpRespBody io.ReadCloser = httpResp.Body
-	defer func() {
-		if httpRespBody != nil {
-			httpRespBody.Close()
-		}
-	}()
+	var httpRespBody io.Reader = httpResp.Body
 	dataList := make([]base.Data, 0)
 	errs := make([]error, 0)
 	doc, err := goquery.NewDocumentFromReader(httpRespBody)
@@ -130,65 +185,20 @@ func getItemProcessors() []pipeline.ProcessItem {
 	return itemProcessors
 }
 
-func parseForRequest(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
-	if httpResp.StatusCode != 200 {
-		err := errors.New(fmt.Sprintf("Unsupported status code %d. (httpResponse=%v)", httpResp))
-		return nil, []error{err}
-	}
-
-	// var reqUrl *url.URL = httpResp.Request.URL
-	var httpRespBody io.ReadCloser = httpResp.Body
-	defer func() {
-		if httpRespBody != nil {
-			httpRespBody.Close()
-		}
-	}()
-
-	dataList := make([]base.Data, 0)
-	errs := make([]error, 0)
-	if !gotPage {
-		dataList, errs = parseForPage(httpResp, respDepth)
-	}
-	doc, err := goquery.NewDocumentFromReader(httpRespBody)
-	if err != nil {
-		errs = append(errs, err)
-		return dataList, errs
-	}
-
-	doc.Find(".zm-item").Each(func(index int, sel *goquery.Selection) {
-		imap := make(map[string]interface{})
-		imap["title"] = sel.Find(".zm-item-title").Text()
-		name := sel.Find(".name").Text()
-		if name != "" {
-			imap["nickname"] = name
-		} else {
-			imap["nickname"] = sel.Find(".author-link").Text()
-			imap["authorinfo"] = sel.Find(".bio").Text()
-		}
-		imap["voters"] = sel.Find(".js-voteCount").Text()
-		content, _ := sel.Find(".content").Html()
-		imap["content"] = content
-		item := base.Item(imap)
-		dataList = append(dataList, &item)
-	})
-	return dataList, errs
-}
+// 分页请求的优先级。
+const pagePriority = 10
 
+// 只从收藏夹的首页生成分页请求。
 func parseForPage(httpResp *http.Response, respDepth uint32) ([]base.Data, []error) {
-	if gotPage {
-		return nil, []error{}
-	}
 	if httpResp.StatusCode != 200 {
 		err := errors.New(fmt.Sprintf("Unsupported status code %d. (httpResponse=%v)", httpResp))
 		return nil, []error{err}
 	}
 	var reqUrl *url.URL = httpResp.Request.URL
-	var httpRespBody io.ReadCloser = httpResp.Body
-	defer func() {
-		if httpRespBody != nil {
-			httpRespBody.Close()
-		}
-	}()
+	if reqUrl.Query().Get("page") != "" {
+		return nil, []error{}
+	}
+	var httpRespBody io.Reader = httpResp.Body
 
 	dataList := make([]base.Data, 0)
 	errs := make([]error, 0)
@@ -213,12 +223,13 @@ func parseForPage(httpResp *http.Response, respDepth uint32) ([]base.Data, []err
 					errs = append(errs, err)
 				} else {
 					req := base.NewRequest(httpReq, respDepth)
+					// 分页的请求优先于页面中的其他链接。
+					req.SetPriority(pagePriority)
 					dataList = append(dataList, req)
 				}
 			}
 		}
 	})
-	gotPage = true
 	return dataList, errs
 }
 